@@ -0,0 +1,70 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// exportTransform returns the matrices the writers apply to positions and
+// normals: the model matrix when bake is set, otherwise identity. mirrored
+// reports whether the model matrix turns the winding inside out.
+func exportTransform(geom *Geometry, bake bool) (model, normal nomath.Mat4, mirrored bool) {
+	if !bake {
+		return nomath.IdentityMatrix(), nomath.IdentityMatrix(), false
+	}
+	geom.Update()
+	model = geom.Transform.ModelMatrix
+	return model, model.Inverse().Transpose(), model.Determinant3x3() < 0
+}
+
+// exportMaterials lists the materials of geom's triangles in order of first
+// use, along with a unique name for each so files can refer to them by name
+func exportMaterials(geom *Geometry) ([]*lookdev.Material, map[*lookdev.Material]string) {
+	var materials []*lookdev.Material
+	names := make(map[*lookdev.Material]string)
+	taken := make(map[string]bool)
+
+	for _, tri := range geom.Triangles {
+		material := triangleMaterial(geom, tri)
+		if _, ok := names[material]; ok {
+			continue
+		}
+		// Names end up in MTL statements and texture file names
+		base := strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) || r == '/' || r == '\\' {
+				return '_'
+			}
+			return r
+		}, material.Name)
+		if base == "" {
+			base = "material"
+		}
+		name := base
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%s_%d", base, i)
+		}
+		taken[name] = true
+		names[material] = name
+		materials = append(materials, material)
+	}
+	return materials, names
+}
+
+func triangleMaterial(geom *Geometry, tri *Triangle) *lookdev.Material {
+	if tri.Material != nil {
+		return tri.Material
+	}
+	return geom.Material
+}
+
+// cornerOrder is the order to write triangle corners in, reversed when the
+// export transform mirrors the geometry
+func cornerOrder(mirrored bool) [3]int {
+	if mirrored {
+		return [3]int{0, 2, 1}
+	}
+	return [3]int{0, 1, 2}
+}
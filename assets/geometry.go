@@ -8,20 +8,26 @@ import (
 type Geometry struct {
 	Name        string
 	Transform   *nomath.Transform
-	Vertices    []*nomath.Vec3
-	Normals     []*nomath.Vec3
-	UVs         []*nomath.Vec2
 	Triangles   []*Triangle
+	Mesh        *Mesh // Vertex data the triangles index into
 	BoundingBox *nomath.BoundingBox
-	Material    *lookdev.Material
+	Material    *lookdev.Material            // Default for faces without a library material
+	Materials   map[string]*lookdev.Material // From the OBJ's mtllib files, by name
+
+	CastShadows    bool // Rendered into light shadow maps
+	ReceiveShadows bool // Tested against light shadow maps when shaded
 }
 
 func (g *Geometry) NewGeometry() *Geometry {
 	geo := &Geometry{
 		Name:        "Object001",
 		Transform:   nomath.NewTransform(),
+		Mesh:        &Mesh{},
 		BoundingBox: nomath.NewBoundingBox(),
 		Material:    lookdev.NewMaterial("DefaultMaterial"),
+
+		CastShadows:    true,
+		ReceiveShadows: true,
 	}
 	geo.ComputeBoundingBox()
 	return geo
@@ -38,14 +44,15 @@ func (g *Geometry) Update() {
 }
 
 func (g *Geometry) ComputeBoundingBox() {
-	if len(g.Vertices) == 0 {
+	if g.Mesh == nil || len(g.Mesh.Positions) == 0 {
 		return
 	}
+	positions := g.Mesh.Positions
 
-	min := *g.Vertices[0]
-	max := *g.Vertices[0]
+	min := positions[0]
+	max := positions[0]
 
-	for _, v := range g.Vertices[1:] {
+	for _, v := range positions[1:] {
 		if v.X < min.X {
 			min.X = v.X
 		}
@@ -70,17 +77,18 @@ func (g *Geometry) ComputeBoundingBox() {
 }
 
 func (g *Geometry) ComputeTransformedBoundingBox() {
-	if len(g.Vertices) == 0 {
+	if g.Mesh == nil || len(g.Mesh.Positions) == 0 {
 		return
 	}
+	positions := g.Mesh.Positions
 
 	transform := g.Transform.GetMatrix()
 
-	first := transform.MultiplyVec4(g.Vertices[0].ToVec4(1)).ToVec3()
+	first := transform.MultiplyVec4(positions[0].ToVec4(1)).ToVec3()
 	min := first
 	max := first
 
-	for _, v := range g.Vertices[1:] {
+	for _, v := range positions[1:] {
 		tv := transform.MultiplyVec4(v.ToVec4(1)).ToVec3()
 		min = nomath.Min(min, tv)
 		max = nomath.Max(max, tv)
@@ -0,0 +1,915 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GLTFScene is everything LoadGLTF found in the default scene of a file
+type GLTFScene struct {
+	Geometries []*Geometry
+	Cameras    []*GLTFCamera
+	Lights     []*GLTFLight
+}
+
+// GLTFCamera is a perspective camera placed in world space
+type GLTFCamera struct {
+	Name        string
+	Position    nomath.Vec3
+	Rotation    nomath.Mat4 // World rotation, the camera looks down its -Z
+	YFov        float64     // Vertical field of view in radians
+	AspectRatio float64     // 0 when the file leaves it to the viewport
+	ZNear       float64
+	ZFar        float64 // 0 for an infinite projection
+}
+
+// GLTFLight is a KHR_lights_punctual light placed in world space
+type GLTFLight struct {
+	Name           string
+	Type           string // "directional", "point" or "spot"
+	Position       nomath.Vec3
+	Rotation       nomath.Mat4 // World rotation, the light shines down its -Z
+	Color          nomath.Vec3 // Linear RGB in [0, 1]
+	Intensity      float64     // Candela for point and spot lights, lux for directional
+	Range          float64     // 0 means unlimited
+	InnerConeAngle float64     // Spot half-angles in radians
+	OuterConeAngle float64
+}
+
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A
+	glbChunkBIN  = 0x004E4942
+)
+
+// Accessor component types
+const (
+	gltfByte          = 5120
+	gltfUnsignedByte  = 5121
+	gltfShort         = 5122
+	gltfUnsignedShort = 5123
+	gltfUnsignedInt   = 5125
+	gltfFloat         = 5126
+)
+
+// Primitive modes that produce triangles
+const (
+	gltfTriangles     = 4
+	gltfTriangleStrip = 5
+	gltfTriangleFan   = 6
+)
+
+var gltfComponentCounts = map[string]int{
+	"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT2": 4, "MAT3": 9, "MAT4": 16,
+}
+
+var gltfComponentSizes = map[int]int{
+	gltfByte: 1, gltfUnsignedByte: 1, gltfShort: 2, gltfUnsignedShort: 2, gltfUnsignedInt: 4, gltfFloat: 4,
+}
+
+// Extensions a file may list as required and still load correctly
+var gltfSupportedExtensions = map[string]bool{
+	"KHR_lights_punctual": true,
+}
+
+type gltfDocument struct {
+	ExtensionsRequired []string         `json:"extensionsRequired"`
+	Scene              *int             `json:"scene"`
+	Scenes             []gltfSceneDef   `json:"scenes"`
+	Nodes              []gltfNode       `json:"nodes"`
+	Meshes             []gltfMesh       `json:"meshes"`
+	Accessors          []gltfAccessor   `json:"accessors"`
+	BufferViews        []gltfBufferView `json:"bufferViews"`
+	Buffers            []gltfBuffer     `json:"buffers"`
+	Materials          []gltfMaterial   `json:"materials"`
+	Textures           []gltfTexture    `json:"textures"`
+	Images             []gltfImage      `json:"images"`
+	Cameras            []gltfCameraDef  `json:"cameras"`
+	Extensions         struct {
+		Lights *struct {
+			Lights []gltfLightDef `json:"lights"`
+		} `json:"KHR_lights_punctual"`
+	} `json:"extensions"`
+}
+
+type gltfSceneDef struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Name        string      `json:"name"`
+	Children    []int       `json:"children"`
+	Mesh        *int        `json:"mesh"`
+	Camera      *int        `json:"camera"`
+	Matrix      []float64   `json:"matrix"` // Column-major, like nomath.Mat4
+	Translation *[3]float64 `json:"translation"`
+	Rotation    *[4]float64 `json:"rotation"` // Quaternion x, y, z, w
+	Scale       *[3]float64 `json:"scale"`
+	Extensions  struct {
+		Light *struct {
+			Light int `json:"light"`
+		} `json:"KHR_lights_punctual"`
+	} `json:"extensions"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type gltfBuffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type gltfMaterial struct {
+	Name string `json:"name"`
+	PBR  *struct {
+		BaseColorFactor          *[4]float64     `json:"baseColorFactor"`
+		BaseColorTexture         *gltfTextureRef `json:"baseColorTexture"`
+		MetallicFactor           *float64        `json:"metallicFactor"`
+		RoughnessFactor          *float64        `json:"roughnessFactor"`
+		MetallicRoughnessTexture *gltfTextureRef `json:"metallicRoughnessTexture"`
+	} `json:"pbrMetallicRoughness"`
+	NormalTexture *gltfTextureRef `json:"normalTexture"`
+	AlphaMode     string          `json:"alphaMode"`
+	AlphaCutoff   *float64        `json:"alphaCutoff"`
+}
+
+type gltfTextureRef struct {
+	Index int `json:"index"`
+}
+
+type gltfTexture struct {
+	Source *int `json:"source"`
+}
+
+type gltfImage struct {
+	URI        string `json:"uri"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type gltfCameraDef struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Perspective *struct {
+		YFov        float64 `json:"yfov"`
+		AspectRatio float64 `json:"aspectRatio"`
+		ZNear       float64 `json:"znear"`
+		ZFar        float64 `json:"zfar"`
+	} `json:"perspective"`
+}
+
+type gltfLightDef struct {
+	Name      string      `json:"name"`
+	Type      string      `json:"type"`
+	Color     *[3]float64 `json:"color"`
+	Intensity *float64    `json:"intensity"`
+	Range     float64     `json:"range"`
+	Spot      *struct {
+		InnerConeAngle float64  `json:"innerConeAngle"`
+		OuterConeAngle *float64 `json:"outerConeAngle"`
+	} `json:"spot"`
+}
+
+// gltfLoader holds the parsed document while its nodes are converted
+type gltfLoader struct {
+	doc       gltfDocument
+	baseDir   string
+	binChunk  []byte // The BIN chunk of a .glb, used by buffers without a uri
+	buffers   [][]byte
+	textures  map[int]*lookdev.Texture // By image index
+	materials map[int]*lookdev.Material
+	scene     *GLTFScene
+}
+
+// LoadGLTF loads a glTF 2.0 file, either .gltf JSON with external or embedded
+// buffers or a binary .glb, and returns the meshes, cameras and punctual
+// lights of its default scene. Each mesh keeps its own vertices, with the
+// node's world placement on the Geometry's Transform.
+func LoadGLTF(path string) (*GLTFScene, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	loader := &gltfLoader{
+		baseDir:   filepath.Dir(path),
+		textures:  make(map[int]*lookdev.Texture),
+		materials: make(map[int]*lookdev.Material),
+		scene:     &GLTFScene{},
+	}
+
+	jsonData := data
+	if len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic {
+		jsonData, loader.binChunk, err = parseGLB(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GLB file %s: %v", path, err)
+		}
+	}
+	if err := json.Unmarshal(jsonData, &loader.doc); err != nil {
+		return nil, fmt.Errorf("invalid glTF file %s: %v", path, err)
+	}
+	for _, ext := range loader.doc.ExtensionsRequired {
+		if !gltfSupportedExtensions[ext] {
+			return nil, fmt.Errorf("%s requires unsupported extension %s", path, ext)
+		}
+	}
+
+	if err := loader.loadBuffers(); err != nil {
+		return nil, err
+	}
+	if err := loader.loadScene(); err != nil {
+		return nil, err
+	}
+	return loader.scene, nil
+}
+
+// parseGLB splits a binary glTF into its JSON and BIN chunks
+func parseGLB(data []byte) ([]byte, []byte, error) {
+	if len(data) < 12 {
+		return nil, nil, fmt.Errorf("truncated header")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+		return nil, nil, fmt.Errorf("unsupported version %d", version)
+	}
+	length := int(binary.LittleEndian.Uint32(data[8:]))
+	if length > len(data) {
+		return nil, nil, fmt.Errorf("file is %d bytes but the header says %d", len(data), length)
+	}
+
+	var jsonChunk, binChunk []byte
+	for offset := 12; offset+8 <= length; {
+		chunkLength := int(binary.LittleEndian.Uint32(data[offset:]))
+		chunkType := binary.LittleEndian.Uint32(data[offset+4:])
+		start := offset + 8
+		if start+chunkLength > length {
+			return nil, nil, fmt.Errorf("chunk at byte %d runs past the end of the file", offset)
+		}
+		switch chunkType {
+		case glbChunkJSON:
+			jsonChunk = data[start : start+chunkLength]
+		case glbChunkBIN:
+			binChunk = data[start : start+chunkLength]
+		}
+		offset = start + chunkLength
+	}
+	if jsonChunk == nil {
+		return nil, nil, fmt.Errorf("missing JSON chunk")
+	}
+	return jsonChunk, binChunk, nil
+}
+
+func (l *gltfLoader) loadBuffers() error {
+	l.buffers = make([][]byte, len(l.doc.Buffers))
+	for i, buffer := range l.doc.Buffers {
+		var data []byte
+		if buffer.URI == "" {
+			if i != 0 || l.binChunk == nil {
+				return fmt.Errorf("buffer %d has no uri and there is no GLB BIN chunk", i)
+			}
+			data = l.binChunk
+		} else {
+			var err error
+			data, err = l.readURI(buffer.URI)
+			if err != nil {
+				return fmt.Errorf("buffer %d: %v", i, err)
+			}
+		}
+		if len(data) < buffer.ByteLength {
+			return fmt.Errorf("buffer %d: expected %d bytes, got %d", i, buffer.ByteLength, len(data))
+		}
+		l.buffers[i] = data
+	}
+	return nil
+}
+
+// readURI returns the contents of a base64 data URI or of a file relative to
+// the glTF file
+func (l *gltfLoader) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.IndexByte(uri, ',')
+		if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+			return nil, fmt.Errorf("unsupported data URI")
+		}
+		return base64.StdEncoding.DecodeString(uri[comma+1:])
+	}
+	path, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(l.baseDir, path)
+	}
+	return os.ReadFile(path)
+}
+
+func (l *gltfLoader) loadScene() error {
+	var roots []int
+	switch {
+	case l.doc.Scene != nil && *l.doc.Scene < len(l.doc.Scenes):
+		roots = l.doc.Scenes[*l.doc.Scene].Nodes
+	case len(l.doc.Scenes) > 0:
+		roots = l.doc.Scenes[0].Nodes
+	default:
+		// No scenes, so every node that isn't somebody's child is a root
+		isChild := make([]bool, len(l.doc.Nodes))
+		for _, node := range l.doc.Nodes {
+			for _, child := range node.Children {
+				if child >= 0 && child < len(isChild) {
+					isChild[child] = true
+				}
+			}
+		}
+		for i := range l.doc.Nodes {
+			if !isChild[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+
+	for _, root := range roots {
+		if err := l.loadNode(root, nomath.IdentityMatrix(), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *gltfLoader) loadNode(index int, parent nomath.Mat4, depth int) error {
+	if index < 0 || index >= len(l.doc.Nodes) {
+		return fmt.Errorf("node %d does not exist", index)
+	}
+	if depth > len(l.doc.Nodes) {
+		return fmt.Errorf("node %d is part of a cycle", index)
+	}
+	node := &l.doc.Nodes[index]
+	world := parent.Multiply(node.localMatrix())
+
+	if node.Mesh != nil {
+		geom, err := l.loadMesh(*node.Mesh, world)
+		if err != nil {
+			return fmt.Errorf("node %d: %v", index, err)
+		}
+		if geom != nil {
+			if node.Name != "" {
+				geom.Name = node.Name
+			}
+			l.scene.Geometries = append(l.scene.Geometries, geom)
+		}
+	}
+	if node.Camera != nil {
+		if err := l.loadCamera(*node.Camera, world); err != nil {
+			return fmt.Errorf("node %d: %v", index, err)
+		}
+	}
+	if node.Extensions.Light != nil {
+		if err := l.loadLight(node.Extensions.Light.Light, world); err != nil {
+			return fmt.Errorf("node %d: %v", index, err)
+		}
+	}
+
+	for _, child := range node.Children {
+		if err := l.loadNode(child, world, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localMatrix is the node's matrix, or T * R * S from its TRS properties
+func (n *gltfNode) localMatrix() nomath.Mat4 {
+	if len(n.Matrix) == 16 {
+		var m nomath.Mat4
+		copy(m[:], n.Matrix)
+		return m
+	}
+	m := nomath.IdentityMatrix()
+	if n.Translation != nil {
+		m = m.Multiply(nomath.TranslationMatrix(n.Translation[0], n.Translation[1], n.Translation[2]))
+	}
+	if n.Rotation != nil {
+		m = m.Multiply(nomath.QuaternionMatrix(n.Rotation[0], n.Rotation[1], n.Rotation[2], n.Rotation[3]))
+	}
+	if n.Scale != nil {
+		m = m.Multiply(nomath.ScaleMatrix(n.Scale[0], n.Scale[1], n.Scale[2]))
+	}
+	return m
+}
+
+// loadMesh builds one Geometry from all triangle primitives of a mesh. It
+// returns nil when the mesh only has points or lines.
+func (l *gltfLoader) loadMesh(index int, world nomath.Mat4) (*Geometry, error) {
+	if index < 0 || index >= len(l.doc.Meshes) {
+		return nil, fmt.Errorf("mesh %d does not exist", index)
+	}
+	mesh := &l.doc.Meshes[index]
+
+	name := mesh.Name
+	if name == "" {
+		name = fmt.Sprintf("mesh%d", index)
+	}
+	geom := &Geometry{
+		Name:        name,
+		Transform:   nomath.NewTransform(),
+		BoundingBox: nomath.NewBoundingBox(),
+		Material:    lookdev.NewMaterial(name + "_material"),
+		Materials:   make(map[string]*lookdev.Material),
+
+		CastShadows:    true,
+		ReceiveShadows: true,
+	}
+
+	// The world matrix is split into the Transform's position, rotation and
+	// scale, and the vertices stay in the mesh's own space. A Transform can't
+	// mirror, so a negative scale flips the local X axis instead.
+	basis := world
+	mirrored := basis.Determinant3x3() < 0
+	flip := 1.0
+	if mirrored {
+		flip = -1
+		basis[0], basis[1], basis[2] = -basis[0], -basis[1], -basis[2]
+	}
+	geom.Transform.SetPosition(nomath.Vec3{X: world[12], Y: world[13], Z: world[14]})
+	geom.Transform.SetRotation(nomath.RotationFromMatrix(rotationOnly(basis)))
+	geom.Transform.SetScale(nomath.Vec3{
+		X: nomath.Vec3{X: basis[0], Y: basis[1], Z: basis[2]}.Length(),
+		Y: nomath.Vec3{X: basis[4], Y: basis[5], Z: basis[6]}.Length(),
+		Z: nomath.Vec3{X: basis[8], Y: basis[9], Z: basis[10]}.Length(),
+	})
+
+	builder := newMeshBuilder(geom)
+	hasNormals := true
+	for p, prim := range mesh.Primitives {
+		mode := gltfTriangles
+		if prim.Mode != nil {
+			mode = *prim.Mode
+		}
+		if mode != gltfTriangles && mode != gltfTriangleStrip && mode != gltfTriangleFan {
+			continue
+		}
+
+		positionIndex, ok := prim.Attributes["POSITION"]
+		if !ok {
+			return nil, fmt.Errorf("mesh %d primitive %d has no POSITION", index, p)
+		}
+		positions, size, err := l.readAccessor(positionIndex)
+		if err != nil {
+			return nil, fmt.Errorf("mesh %d primitive %d POSITION: %v", index, p, err)
+		}
+		if size != 3 {
+			return nil, fmt.Errorf("mesh %d primitive %d: POSITION must be VEC3", index, p)
+		}
+		count := len(positions) / 3
+
+		// Attributes are appended after those of earlier primitives, at these offsets
+		base := meshCorner{position: len(builder.positions), normal: -1, uv: -1, color: -1}
+		for i := 0; i < count; i++ {
+			builder.positions = append(builder.positions, nomath.Vec3{X: flip * positions[i*3], Y: positions[i*3+1], Z: positions[i*3+2]})
+		}
+
+		if normalIndex, ok := prim.Attributes["NORMAL"]; ok {
+			data, size, err := l.readAccessor(normalIndex)
+			if err != nil {
+				return nil, fmt.Errorf("mesh %d primitive %d NORMAL: %v", index, p, err)
+			}
+			if size != 3 || len(data) != count*3 {
+				return nil, fmt.Errorf("mesh %d primitive %d: NORMAL does not match POSITION", index, p)
+			}
+			base.normal = len(builder.normals)
+			for i := 0; i < count; i++ {
+				n := nomath.Vec3{X: flip * data[i*3], Y: data[i*3+1], Z: data[i*3+2]}.Normalize()
+				builder.normals = append(builder.normals, n)
+			}
+		} else {
+			hasNormals = false
+		}
+
+		if uvIndex, ok := prim.Attributes["TEXCOORD_0"]; ok {
+			data, size, err := l.readAccessor(uvIndex)
+			if err != nil {
+				return nil, fmt.Errorf("mesh %d primitive %d TEXCOORD_0: %v", index, p, err)
+			}
+			if size != 2 || len(data) != count*2 {
+				return nil, fmt.Errorf("mesh %d primitive %d: TEXCOORD_0 does not match POSITION", index, p)
+			}
+			base.uv = len(builder.uvs)
+			for i := 0; i < count; i++ {
+				// glTF puts the UV origin at the top-left, Texture.Sample expects bottom-left
+				builder.uvs = append(builder.uvs, nomath.Vec2{U: data[i*2], V: 1 - data[i*2+1]})
+			}
+		}
+
+		if colorIndex, ok := prim.Attributes["COLOR_0"]; ok {
+			data, size, err := l.readAccessor(colorIndex)
+			if err != nil {
+				return nil, fmt.Errorf("mesh %d primitive %d COLOR_0: %v", index, p, err)
+			}
+			if (size != 3 && size != 4) || len(data) != count*size {
+				return nil, fmt.Errorf("mesh %d primitive %d: COLOR_0 does not match POSITION", index, p)
+			}
+			base.color = len(builder.colors)
+			for i := 0; i < count; i++ {
+				color := lookdev.NewColorRGBFloat(data[i*size], data[i*size+1], data[i*size+2])
+				if size == 4 {
+					color.A = data[i*size+3]
+				}
+				builder.colors = append(builder.colors, *color)
+			}
+		}
+
+		var indices []int
+		if prim.Indices != nil {
+			data, size, err := l.readAccessor(*prim.Indices)
+			if err != nil {
+				return nil, fmt.Errorf("mesh %d primitive %d indices: %v", index, p, err)
+			}
+			if size != 1 {
+				return nil, fmt.Errorf("mesh %d primitive %d: indices must be SCALAR", index, p)
+			}
+			indices = make([]int, len(data))
+			for i, value := range data {
+				indices[i] = int(value)
+				if indices[i] < 0 || indices[i] >= count {
+					return nil, fmt.Errorf("mesh %d primitive %d: index %d out of range", index, p, indices[i])
+				}
+			}
+		} else {
+			indices = make([]int, count)
+			for i := range indices {
+				indices[i] = i
+			}
+		}
+
+		material := geom.Material
+		if prim.Material != nil {
+			material, err = l.loadMaterial(*prim.Material)
+			if err != nil {
+				return nil, fmt.Errorf("mesh %d primitive %d: %v", index, p, err)
+			}
+			geom.Materials[material.Name] = material
+			if p == 0 {
+				geom.Material = material
+			}
+		}
+
+		for _, corners := range triangulate(indices, mode) {
+			i0, i1, i2 := corners[0], corners[1], corners[2]
+			if mirrored {
+				// A negative scale turns the winding inside out
+				i1, i2 = i2, i1
+			}
+			builder.addTriangle(material, base.offset(i0), base.offset(i1), base.offset(i2))
+		}
+	}
+
+	if len(builder.triangles) == 0 {
+		return nil, nil
+	}
+	builder.build()
+	if !hasNormals {
+		geom.CalculateNormals()
+	}
+	geom.CalculateTangents()
+	geom.ComputeBoundingBox()
+	return geom, nil
+}
+
+// triangulate turns the indices of a triangle list, strip or fan into
+// counter-clockwise triangles
+func triangulate(indices []int, mode int) [][3]int {
+	var triangles [][3]int
+	switch mode {
+	case gltfTriangles:
+		for i := 0; i+2 < len(indices); i += 3 {
+			triangles = append(triangles, [3]int{indices[i], indices[i+1], indices[i+2]})
+		}
+	case gltfTriangleStrip:
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				triangles = append(triangles, [3]int{indices[i], indices[i+1], indices[i+2]})
+			} else {
+				triangles = append(triangles, [3]int{indices[i+1], indices[i], indices[i+2]})
+			}
+		}
+	case gltfTriangleFan:
+		for i := 1; i+1 < len(indices); i++ {
+			triangles = append(triangles, [3]int{indices[0], indices[i], indices[i+1]})
+		}
+	}
+	return triangles
+}
+
+// readAccessor returns the accessor's elements as a flat slice of floats
+// along with the number of components per element
+func (l *gltfLoader) readAccessor(index int) ([]float64, int, error) {
+	if index < 0 || index >= len(l.doc.Accessors) {
+		return nil, 0, fmt.Errorf("accessor %d does not exist", index)
+	}
+	accessor := &l.doc.Accessors[index]
+	if len(accessor.Sparse) > 0 {
+		return nil, 0, fmt.Errorf("accessor %d: sparse accessors are not supported", index)
+	}
+	components, ok := gltfComponentCounts[accessor.Type]
+	if !ok {
+		return nil, 0, fmt.Errorf("accessor %d: unknown type %q", index, accessor.Type)
+	}
+	componentSize, ok := gltfComponentSizes[accessor.ComponentType]
+	if !ok {
+		return nil, 0, fmt.Errorf("accessor %d: unknown component type %d", index, accessor.ComponentType)
+	}
+
+	values := make([]float64, accessor.Count*components)
+	if accessor.BufferView == nil {
+		return values, components, nil // No data means all zeros
+	}
+	if *accessor.BufferView < 0 || *accessor.BufferView >= len(l.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("accessor %d: buffer view %d does not exist", index, *accessor.BufferView)
+	}
+	view := &l.doc.BufferViews[*accessor.BufferView]
+	if view.Buffer < 0 || view.Buffer >= len(l.buffers) {
+		return nil, 0, fmt.Errorf("accessor %d: buffer %d does not exist", index, view.Buffer)
+	}
+
+	stride := view.ByteStride
+	if stride == 0 {
+		stride = components * componentSize
+	}
+	start := view.ByteOffset + accessor.ByteOffset
+	if accessor.Count > 0 {
+		end := start + (accessor.Count-1)*stride + components*componentSize
+		if end > view.ByteOffset+view.ByteLength || end > len(l.buffers[view.Buffer]) {
+			return nil, 0, fmt.Errorf("accessor %d runs past the end of its buffer view", index)
+		}
+	}
+	data := l.buffers[view.Buffer]
+
+	for i := 0; i < accessor.Count; i++ {
+		element := start + i*stride
+		for c := 0; c < components; c++ {
+			offset := element + c*componentSize
+			values[i*components+c] = readComponent(data[offset:], accessor.ComponentType, accessor.Normalized)
+		}
+	}
+	return values, components, nil
+}
+
+// readComponent decodes one little-endian value, mapping normalized
+// integers to [0, 1] or [-1, 1]
+func readComponent(data []byte, componentType int, normalized bool) float64 {
+	switch componentType {
+	case gltfByte:
+		value := float64(int8(data[0]))
+		if normalized {
+			return math.Max(value/127, -1)
+		}
+		return value
+	case gltfUnsignedByte:
+		value := float64(data[0])
+		if normalized {
+			return value / 255
+		}
+		return value
+	case gltfShort:
+		value := float64(int16(binary.LittleEndian.Uint16(data)))
+		if normalized {
+			return math.Max(value/32767, -1)
+		}
+		return value
+	case gltfUnsignedShort:
+		value := float64(binary.LittleEndian.Uint16(data))
+		if normalized {
+			return value / 65535
+		}
+		return value
+	case gltfUnsignedInt:
+		return float64(binary.LittleEndian.Uint32(data))
+	}
+	return float64(math.Float32frombits(binary.LittleEndian.Uint32(data)))
+}
+
+// loadMaterial maps a metallic-roughness material onto the Phong parameters
+// the renderer uses. Dielectrics get a dim white highlight and metals one
+// tinted by their base color; rougher surfaces get a wider highlight.
+func (l *gltfLoader) loadMaterial(index int) (*lookdev.Material, error) {
+	if material, ok := l.materials[index]; ok {
+		return material, nil
+	}
+	if index < 0 || index >= len(l.doc.Materials) {
+		return nil, fmt.Errorf("material %d does not exist", index)
+	}
+	def := &l.doc.Materials[index]
+
+	name := def.Name
+	if name == "" {
+		name = fmt.Sprintf("material%d", index)
+	}
+	material := lookdev.NewMaterial(name)
+
+	baseColor := [4]float64{1, 1, 1, 1}
+	material.Metallic, material.Roughness = 1, 1
+	if def.PBR != nil {
+		if def.PBR.BaseColorFactor != nil {
+			baseColor = *def.PBR.BaseColorFactor
+		}
+		if def.PBR.MetallicFactor != nil {
+			material.Metallic = *def.PBR.MetallicFactor
+		}
+		if def.PBR.RoughnessFactor != nil {
+			material.Roughness = *def.PBR.RoughnessFactor
+		}
+		if def.PBR.BaseColorTexture != nil {
+			tex, err := l.loadTexture(def.PBR.BaseColorTexture.Index)
+			if err != nil {
+				return nil, fmt.Errorf("material %s: %v", name, err)
+			}
+			material.DiffuseTexture = tex
+		}
+		if def.PBR.MetallicRoughnessTexture != nil {
+			tex, err := l.loadTexture(def.PBR.MetallicRoughnessTexture.Index)
+			if err != nil {
+				return nil, fmt.Errorf("material %s: %v", name, err)
+			}
+			material.MetallicRoughnessTexture = tex
+		}
+	}
+	if def.NormalTexture != nil {
+		tex, err := l.loadTexture(def.NormalTexture.Index)
+		if err != nil {
+			return nil, fmt.Errorf("material %s: %v", name, err)
+		}
+		material.NormalTexture = tex
+	}
+
+	material.DiffuseColor = *lookdev.NewColorRGBFloat(baseColor[0], baseColor[1], baseColor[2])
+	specular := func(base float64) float64 {
+		return 0.04 + (base-0.04)*material.Metallic
+	}
+	material.SpecularColor = *lookdev.NewColorRGBFloat(specular(baseColor[0]), specular(baseColor[1]), specular(baseColor[2]))
+	alpha := math.Max(material.Roughness*material.Roughness, 0.05)
+	material.Shininess = math.Max(1, 2/(alpha*alpha)-2)
+
+	switch def.AlphaMode {
+	case "MASK":
+		material.AlphaCutoff = 0.5
+		if def.AlphaCutoff != nil {
+			material.AlphaCutoff = *def.AlphaCutoff
+		}
+	case "BLEND":
+		material.DiffuseColor.A = baseColor[3]
+	default:
+		// Opaque materials ignore texture alpha, so don't let it turn on blending
+		if material.DiffuseTexture != nil && material.DiffuseTexture.HasAlpha {
+			opaque := *material.DiffuseTexture
+			opaque.HasAlpha = false
+			material.DiffuseTexture = &opaque
+		}
+	}
+
+	l.materials[index] = material
+	return material, nil
+}
+
+func (l *gltfLoader) loadTexture(index int) (*lookdev.Texture, error) {
+	if index < 0 || index >= len(l.doc.Textures) || l.doc.Textures[index].Source == nil {
+		return nil, fmt.Errorf("texture %d does not exist or has no image", index)
+	}
+	source := *l.doc.Textures[index].Source
+	if tex, ok := l.textures[source]; ok {
+		return tex, nil
+	}
+	if source < 0 || source >= len(l.doc.Images) {
+		return nil, fmt.Errorf("image %d does not exist", source)
+	}
+	image := &l.doc.Images[source]
+
+	var data []byte
+	switch {
+	case image.BufferView != nil:
+		viewIndex := *image.BufferView
+		if viewIndex < 0 || viewIndex >= len(l.doc.BufferViews) {
+			return nil, fmt.Errorf("image %d: buffer view %d does not exist", source, viewIndex)
+		}
+		view := &l.doc.BufferViews[viewIndex]
+		if view.Buffer < 0 || view.Buffer >= len(l.buffers) || view.ByteOffset+view.ByteLength > len(l.buffers[view.Buffer]) {
+			return nil, fmt.Errorf("image %d: buffer view %d is out of range", source, viewIndex)
+		}
+		data = l.buffers[view.Buffer][view.ByteOffset : view.ByteOffset+view.ByteLength]
+	case image.URI != "":
+		var err error
+		data, err = l.readURI(image.URI)
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %v", source, err)
+		}
+	default:
+		return nil, fmt.Errorf("image %d has no data", source)
+	}
+
+	tex, err := lookdev.DecodeTexture(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("image %d: %v", source, err)
+	}
+	l.textures[source] = tex
+	return tex, nil
+}
+
+func (l *gltfLoader) loadCamera(index int, world nomath.Mat4) error {
+	if index < 0 || index >= len(l.doc.Cameras) {
+		return fmt.Errorf("camera %d does not exist", index)
+	}
+	def := &l.doc.Cameras[index]
+	if def.Perspective == nil {
+		return nil // Orthographic cameras have nothing to map onto
+	}
+	l.scene.Cameras = append(l.scene.Cameras, &GLTFCamera{
+		Name:        def.Name,
+		Position:    nomath.Vec3{X: world[12], Y: world[13], Z: world[14]},
+		Rotation:    rotationOnly(world),
+		YFov:        def.Perspective.YFov,
+		AspectRatio: def.Perspective.AspectRatio,
+		ZNear:       def.Perspective.ZNear,
+		ZFar:        def.Perspective.ZFar,
+	})
+	return nil
+}
+
+func (l *gltfLoader) loadLight(index int, world nomath.Mat4) error {
+	if l.doc.Extensions.Lights == nil || index < 0 || index >= len(l.doc.Extensions.Lights.Lights) {
+		return fmt.Errorf("light %d does not exist", index)
+	}
+	def := &l.doc.Extensions.Lights.Lights[index]
+
+	light := &GLTFLight{
+		Name:           def.Name,
+		Type:           def.Type,
+		Position:       nomath.Vec3{X: world[12], Y: world[13], Z: world[14]},
+		Rotation:       rotationOnly(world),
+		Color:          nomath.Vec3{X: 1, Y: 1, Z: 1},
+		Intensity:      1,
+		Range:          def.Range,
+		OuterConeAngle: math.Pi / 4,
+	}
+	if def.Color != nil {
+		light.Color = nomath.Vec3{X: def.Color[0], Y: def.Color[1], Z: def.Color[2]}
+	}
+	if def.Intensity != nil {
+		light.Intensity = *def.Intensity
+	}
+	if def.Spot != nil {
+		light.InnerConeAngle = def.Spot.InnerConeAngle
+		if def.Spot.OuterConeAngle != nil {
+			light.OuterConeAngle = *def.Spot.OuterConeAngle
+		}
+	}
+	switch light.Type {
+	case "directional", "point", "spot":
+	default:
+		return fmt.Errorf("light %d has unknown type %q", index, def.Type)
+	}
+	l.scene.Lights = append(l.scene.Lights, light)
+	return nil
+}
+
+// rotationOnly strips the translation and scale from a world matrix
+func rotationOnly(m nomath.Mat4) nomath.Mat4 {
+	r := nomath.IdentityMatrix()
+	for c := 0; c < 3; c++ {
+		axis := nomath.Vec3{X: m[c*4], Y: m[c*4+1], Z: m[c*4+2]}.Normalize()
+		r[c*4], r[c*4+1], r[c*4+2] = axis.X, axis.Y, axis.Z
+	}
+	return r
+}
@@ -0,0 +1,272 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// gltfQuadBuffer holds a unit quad: four float positions, four UVs with the
+// glTF top-left origin and six uint16 indices
+func gltfQuadBuffer() []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0})
+	binary.Write(&buf, binary.LittleEndian, []float32{0, 1, 1, 1, 1, 0, 0, 0})
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, 2, 0, 2, 3})
+	return buf.Bytes()
+}
+
+// gltfQuadDocument is a scene with the quad moved and scaled, a mirrored and
+// turned copy of it, a camera under the first and a point light. An empty uri means
+// the buffer is a GLB BIN chunk.
+func gltfQuadDocument(uri string) map[string]any {
+	buffer := map[string]any{"byteLength": len(gltfQuadBuffer())}
+	if uri != "" {
+		buffer["uri"] = uri
+	}
+	return map[string]any{
+		"asset":  map[string]any{"version": "2.0"},
+		"scene":  0,
+		"scenes": []any{map[string]any{"nodes": []int{0, 2, 3}}},
+		"nodes": []any{
+			map[string]any{"name": "floor", "mesh": 0, "translation": []float64{1, 2, 3}, "scale": []float64{2, 2, 2}, "children": []int{1}},
+			map[string]any{"camera": 0, "translation": []float64{0, 0, 5}},
+			map[string]any{"name": "mirror", "mesh": 0, "rotation": []float64{0, math.Sqrt2 / 2, 0, math.Sqrt2 / 2}, "scale": []float64{-1, 1, 1}},
+			map[string]any{"translation": []float64{0, 10, 0}, "extensions": map[string]any{"KHR_lights_punctual": map[string]any{"light": 0}}},
+		},
+		"meshes": []any{map[string]any{"primitives": []any{map[string]any{
+			"attributes": map[string]any{"POSITION": 0, "TEXCOORD_0": 1},
+			"indices":    2,
+			"material":   0,
+		}}}},
+		"materials": []any{map[string]any{
+			"name":                 "red",
+			"pbrMetallicRoughness": map[string]any{"baseColorFactor": []float64{1, 0, 0, 1}, "metallicFactor": 0, "roughnessFactor": 0.5},
+		}},
+		"accessors": []any{
+			map[string]any{"bufferView": 0, "componentType": gltfFloat, "count": 4, "type": "VEC3"},
+			map[string]any{"bufferView": 1, "componentType": gltfFloat, "count": 4, "type": "VEC2"},
+			map[string]any{"bufferView": 2, "componentType": gltfUnsignedShort, "count": 6, "type": "SCALAR"},
+		},
+		"bufferViews": []any{
+			map[string]any{"buffer": 0, "byteOffset": 0, "byteLength": 48},
+			map[string]any{"buffer": 0, "byteOffset": 48, "byteLength": 32},
+			map[string]any{"buffer": 0, "byteOffset": 80, "byteLength": 12},
+		},
+		"buffers": []any{buffer},
+		"cameras": []any{map[string]any{"type": "perspective", "perspective": map[string]any{"yfov": 0.8, "znear": 0.1}}},
+		"extensions": map[string]any{"KHR_lights_punctual": map[string]any{"lights": []any{
+			map[string]any{"type": "point", "color": []float64{1, 0.5, 0}, "intensity": 20},
+		}}},
+	}
+}
+
+// glbFile packs a document and its binary buffer into a GLB file
+func glbFile(t *testing.T, doc map[string]any, bin []byte) []byte {
+	t.Helper()
+	jsonData, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for len(jsonData)%4 != 0 {
+		jsonData = append(jsonData, ' ')
+	}
+	for len(bin)%4 != 0 {
+		bin = append(bin, 0)
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint32{glbMagic, 2, uint32(12 + 8 + len(jsonData) + 8 + len(bin))})
+	binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(jsonData)), glbChunkJSON})
+	buf.Write(jsonData)
+	binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(bin)), glbChunkBIN})
+	buf.Write(bin)
+	return buf.Bytes()
+}
+
+func writeGLTFDocument(t *testing.T, dir, name string, doc map[string]any) string {
+	t.Helper()
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeTestFile(t, dir, name, string(data))
+}
+
+func TestLoadGLTF(t *testing.T) {
+	dataURI := "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(gltfQuadBuffer())
+	tests := []struct {
+		name  string
+		write func(t *testing.T, dir string) string
+	}{
+		{"embedded buffer", func(t *testing.T, dir string) string {
+			return writeGLTFDocument(t, dir, "quad.gltf", gltfQuadDocument(dataURI))
+		}},
+		{"external buffer", func(t *testing.T, dir string) string {
+			writeTestFile(t, dir, "quad data.bin", string(gltfQuadBuffer()))
+			return writeGLTFDocument(t, dir, "quad.gltf", gltfQuadDocument("quad%20data.bin"))
+		}},
+		{"GLB", func(t *testing.T, dir string) string {
+			return writeTestFile(t, dir, "quad.glb", string(glbFile(t, gltfQuadDocument(""), gltfQuadBuffer())))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scene, err := LoadGLTF(tt.write(t, t.TempDir()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(scene.Geometries) != 2 {
+				t.Fatalf("got %d geometries, want 2", len(scene.Geometries))
+			}
+
+			floor := scene.Geometries[0]
+			if err := floor.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if floor.Name != "floor" || len(floor.Triangles) != 2 || floor.Mesh.VertexCount() != 4 {
+				t.Fatalf("got %q with %d triangles and %d vertices, want floor with 2 and 4", floor.Name, len(floor.Triangles), floor.Mesh.VertexCount())
+			}
+			transform := floor.Transform
+			if transform.Position != (nomath.Vec3{X: 1, Y: 2, Z: 3}) || transform.Rotation != (nomath.Vec3{}) || transform.Scale != (nomath.Vec3{X: 2, Y: 2, Z: 2}) {
+				t.Errorf("got Transform at %v turned %v scaled %v, want the node's TRS", transform.Position, transform.Rotation, transform.Scale)
+			}
+			if floor.BoundingBox.Min != (nomath.Vec3{}) || floor.BoundingBox.Max != (nomath.Vec3{X: 1, Y: 1}) {
+				t.Errorf("bounding box %v, want the untransformed quad", floor.BoundingBox)
+			}
+			for i, p := range floor.Mesh.Positions {
+				// V is flipped to a bottom-left origin
+				if want := (nomath.Vec2{U: p.X, V: p.Y}); floor.Mesh.UVs[i] != want {
+					t.Errorf("vertex at %v has UV %v, want %v", p, floor.Mesh.UVs[i], want)
+				}
+			}
+
+			// The Transforms put the local quads where the node matrices did
+			worlds := []nomath.Mat4{
+				nomath.TranslationMatrix(1, 2, 3).Multiply(nomath.ScaleMatrix(2, 2, 2)),
+				nomath.QuaternionMatrix(0, math.Sqrt2/2, 0, math.Sqrt2/2).Multiply(nomath.ScaleMatrix(-1, 1, 1)),
+			}
+			for i, geom := range scene.Geometries {
+				var want []nomath.Vec3
+				for _, p := range []nomath.Vec3{{}, {X: 1}, {X: 1, Y: 1}, {Y: 1}} {
+					want = append(want, worlds[i].MultiplyVec4(p.ToVec4(1)).ToVec3())
+				}
+				model := geom.Transform.GetMatrix()
+				for _, v := range geom.Mesh.Positions {
+					got := model.MultiplyVec4(v.ToVec4(1)).ToVec3()
+					found := false
+					for _, w := range want {
+						found = found || vec3Near(got, w, 1e-9)
+					}
+					if !found {
+						t.Errorf("%s vertex %v lands at %v, want one of %v", geom.Name, v, got, want)
+					}
+				}
+			}
+			material := floor.Triangles[0].Material
+			if material.Name != "red" || material.DiffuseColor != (lookdev.ColorRGBA{R: 255, A: 1}) {
+				t.Errorf("material %q with color %v, want red", material.Name, material.DiffuseColor)
+			}
+
+			// Both copies still face +Z in their own space, the mirrored one by
+			// flipping its winding
+			for _, geom := range scene.Geometries {
+				for i, tri := range geom.Triangles {
+					if tri.Normal().Z < 0.99 {
+						t.Errorf("%s triangle %d faces %v, want +Z", geom.Name, i, tri.Normal())
+					}
+				}
+			}
+
+			if len(scene.Cameras) != 1 {
+				t.Fatalf("got %d cameras, want 1", len(scene.Cameras))
+			}
+			camera := scene.Cameras[0]
+			if camera.Position != (nomath.Vec3{X: 1, Y: 2, Z: 13}) || math.Abs(camera.YFov-0.8) > 1e-9 {
+				t.Errorf("camera at %v with fov %v, want (1, 2, 13) and 0.8", camera.Position, camera.YFov)
+			}
+			if len(scene.Lights) != 1 {
+				t.Fatalf("got %d lights, want 1", len(scene.Lights))
+			}
+			light := scene.Lights[0]
+			if light.Type != "point" || light.Position != (nomath.Vec3{Y: 10}) || light.Intensity != 20 || light.Color != (nomath.Vec3{X: 1, Y: 0.5}) {
+				t.Errorf("got %+v, want a point light at (0, 10, 0)", light)
+			}
+		})
+	}
+}
+
+func TestLoadGLTFErrors(t *testing.T) {
+	dataURI := "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(gltfQuadBuffer())
+	primitive := func(doc map[string]any) map[string]any {
+		return doc["meshes"].([]any)[0].(map[string]any)["primitives"].([]any)[0].(map[string]any)
+	}
+	tests := []struct {
+		name   string
+		change func(doc map[string]any)
+		want   string
+	}{
+		{"unsupported extension", func(doc map[string]any) {
+			doc["extensionsRequired"] = []string{"KHR_draco_mesh_compression"}
+		}, "requires unsupported extension KHR_draco_mesh_compression"},
+		{"index out of range", func(doc map[string]any) {
+			// Read the positions as byte indices, 1.0 is stored as 00 00 80 3F
+			doc["accessors"].([]any)[2] = map[string]any{"bufferView": 0, "componentType": gltfUnsignedByte, "count": 15, "type": "SCALAR"}
+		}, "mesh 0 primitive 0: index 128 out of range"},
+		{"accessor past the view", func(doc map[string]any) {
+			doc["accessors"].([]any)[0].(map[string]any)["count"] = 5
+		}, "accessor 0 runs past the end of its buffer view"},
+		{"no position", func(doc map[string]any) {
+			primitive(doc)["attributes"] = map[string]any{"TEXCOORD_0": 1}
+		}, "mesh 0 primitive 0 has no POSITION"},
+		{"node cycle", func(doc map[string]any) {
+			doc["nodes"].([]any)[1].(map[string]any)["children"] = []int{0}
+		}, "is part of a cycle"},
+		{"short buffer", func(doc map[string]any) {
+			doc["buffers"].([]any)[0].(map[string]any)["byteLength"] = 200
+		}, "buffer 0: expected 200 bytes, got 92"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := gltfQuadDocument(dataURI)
+			tt.change(doc)
+			_, err := LoadGLTF(writeGLTFDocument(t, t.TempDir(), "bad.gltf", doc))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriangulate(t *testing.T) {
+	tests := []struct {
+		name string
+		mode int
+		want [][3]int
+	}{
+		{"list", gltfTriangles, [][3]int{{0, 1, 2}, {3, 4, 5}}},
+		{"strip", gltfTriangleStrip, [][3]int{{0, 1, 2}, {2, 1, 3}, {2, 3, 4}, {4, 3, 5}}},
+		{"fan", gltfTriangleFan, [][3]int{{0, 1, 2}, {0, 2, 3}, {0, 3, 4}, {0, 4, 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := triangulate([]int{0, 1, 2, 3, 4, 5}, tt.mode)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
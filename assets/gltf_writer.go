@@ -0,0 +1,286 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Buffer view targets
+const (
+	gltfArrayBuffer        = 34962
+	gltfElementArrayBuffer = 34963
+)
+
+// gltfWriter accumulates the binary buffer and the JSON arrays that index it
+type gltfWriter struct {
+	buffer      bytes.Buffer
+	bufferViews []map[string]any
+	accessors   []map[string]any
+	images      []map[string]any
+	textures    []map[string]any
+	textureOf   map[*lookdev.Texture]int
+}
+
+// WriteGLTF writes geom as glTF 2.0: a binary .glb when the path ends in
+// .glb, otherwise .gltf JSON with a .bin buffer next to it. Textures are
+// embedded either way. With bakeTransform the vertices and normals are
+// written in world space, otherwise the Transform becomes the node's matrix.
+func WriteGLTF(geom *Geometry, path string, bakeTransform bool) error {
+	if len(geom.Triangles) == 0 {
+		return fmt.Errorf("%s has no triangles to write", geom.Name)
+	}
+	model, normalMatrix, mirrored := exportTransform(geom, bakeTransform)
+	materials, names := exportMaterials(geom)
+	w := &gltfWriter{textureOf: make(map[*lookdev.Texture]int)}
+
+	// The Mesh's vertices are shared by every primitive, only the indices are
+	// split up
+	mesh := geom.Mesh
+	count := mesh.VertexCount()
+	hasNormals := true
+	for _, n := range mesh.Normals {
+		hasNormals = hasNormals && n != (nomath.Vec3{})
+	}
+	indices := make(map[*lookdev.Material][]uint32)
+	for _, tri := range geom.Triangles {
+		material := triangleMaterial(geom, tri)
+		for _, i := range cornerOrder(mirrored) {
+			indices[material] = append(indices[material], tri.Indices[i])
+		}
+	}
+
+	attributes := map[string]int{}
+	positions := make([]float32, 0, count*3)
+	min := nomath.Vec3{X: math.Inf(1), Y: math.Inf(1), Z: math.Inf(1)}
+	max := nomath.Vec3{X: math.Inf(-1), Y: math.Inf(-1), Z: math.Inf(-1)}
+	for _, position := range mesh.Positions {
+		p := model.MultiplyVec4(position.ToVec4(1)).ToVec3()
+		p = nomath.Vec3{X: float64(float32(p.X)), Y: float64(float32(p.Y)), Z: float64(float32(p.Z))}
+		positions = append(positions, float32(p.X), float32(p.Y), float32(p.Z))
+		min, max = nomath.Min(min, p), nomath.Max(max, p)
+	}
+	attributes["POSITION"] = w.addAccessor(positions, gltfFloat, "VEC3", count, gltfArrayBuffer, map[string]any{
+		"min": []float64{min.X, min.Y, min.Z},
+		"max": []float64{max.X, max.Y, max.Z},
+	})
+	if hasNormals {
+		normals := make([]float32, 0, count*3)
+		for _, normal := range mesh.Normals {
+			n := normalMatrix.TransformVec3(normal).Normalize()
+			normals = append(normals, float32(n.X), float32(n.Y), float32(n.Z))
+		}
+		attributes["NORMAL"] = w.addAccessor(normals, gltfFloat, "VEC3", count, gltfArrayBuffer, nil)
+	}
+	if len(mesh.UVs) > 0 {
+		uvs := make([]float32, 0, count*2)
+		for _, uv := range mesh.UVs {
+			// glTF puts the UV origin at the top-left
+			uvs = append(uvs, float32(uv.U), float32(1-uv.V))
+		}
+		attributes["TEXCOORD_0"] = w.addAccessor(uvs, gltfFloat, "VEC2", count, gltfArrayBuffer, nil)
+	}
+	if len(mesh.Colors) > 0 {
+		colors := make([]uint8, 0, count*4)
+		for _, c := range mesh.Colors {
+			colors = append(colors, c.R, c.G, c.B, uint8(math.Round(c.A*255)))
+		}
+		attributes["COLOR_0"] = w.addAccessor(colors, gltfUnsignedByte, "VEC4", count, gltfArrayBuffer, map[string]any{"normalized": true})
+	}
+
+	var primitives, gltfMaterials []map[string]any
+	for i, material := range materials {
+		gltfMaterial, err := w.material(material, names[material])
+		if err != nil {
+			return err
+		}
+		gltfMaterials = append(gltfMaterials, gltfMaterial)
+		primitives = append(primitives, map[string]any{
+			"attributes": attributes,
+			"indices":    w.addAccessor(indices[material], gltfUnsignedInt, "SCALAR", len(indices[material]), gltfElementArrayBuffer, nil),
+			"material":   i,
+			"mode":       gltfTriangles,
+		})
+	}
+
+	name := filepath.Base(geom.Name)
+	node := map[string]any{"name": name, "mesh": 0}
+	if !bakeTransform {
+		geom.Update()
+		if matrix := geom.Transform.ModelMatrix; matrix != nomath.IdentityMatrix() {
+			node["matrix"] = matrix[:]
+		}
+	}
+
+	doc := map[string]any{
+		"asset":       map[string]any{"version": "2.0", "generator": "GopherEngine"},
+		"scene":       0,
+		"scenes":      []any{map[string]any{"nodes": []int{0}}},
+		"nodes":       []any{node},
+		"meshes":      []any{map[string]any{"name": name, "primitives": primitives}},
+		"materials":   gltfMaterials,
+		"accessors":   w.accessors,
+		"bufferViews": w.bufferViews,
+	}
+	if len(w.images) > 0 {
+		doc["images"] = w.images
+		doc["textures"] = w.textures
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".glb") {
+		doc["buffers"] = []any{map[string]any{"byteLength": w.buffer.Len()}}
+		return writeGLB(path, doc, w.buffer.Bytes())
+	}
+
+	binPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".bin"
+	doc["buffers"] = []any{map[string]any{"byteLength": w.buffer.Len(), "uri": filepath.Base(binPath)}}
+	if err := os.WriteFile(binPath, w.buffer.Bytes(), 0644); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func writeGLB(path string, doc map[string]any, bin []byte) error {
+	jsonData, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	// Chunks are 4-byte aligned, JSON with spaces and BIN with zeros
+	for len(jsonData)%4 != 0 {
+		jsonData = append(jsonData, ' ')
+	}
+	for len(bin)%4 != 0 {
+		bin = append(bin, 0)
+	}
+
+	var out bytes.Buffer
+	length := 12 + 8 + len(jsonData) + 8 + len(bin)
+	binary.Write(&out, binary.LittleEndian, []uint32{glbMagic, 2, uint32(length)})
+	binary.Write(&out, binary.LittleEndian, []uint32{uint32(len(jsonData)), glbChunkJSON})
+	out.Write(jsonData)
+	binary.Write(&out, binary.LittleEndian, []uint32{uint32(len(bin)), glbChunkBIN})
+	out.Write(bin)
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
+
+// addBufferView appends data to the buffer, aligned to 4 bytes, and returns
+// the index of the view that covers it
+func (w *gltfWriter) addBufferView(data []byte, target int) int {
+	for w.buffer.Len()%4 != 0 {
+		w.buffer.WriteByte(0)
+	}
+	view := map[string]any{"buffer": 0, "byteOffset": w.buffer.Len(), "byteLength": len(data)}
+	if target != 0 {
+		view["target"] = target
+	}
+	w.buffer.Write(data)
+	w.bufferViews = append(w.bufferViews, view)
+	return len(w.bufferViews) - 1
+}
+
+// addAccessor stores values (a slice of fixed-size numbers) in a new buffer
+// view and returns the index of an accessor that reads them
+func (w *gltfWriter) addAccessor(values any, componentType int, accessorType string, count, target int, extra map[string]any) int {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, values)
+	accessor := map[string]any{
+		"bufferView":    w.addBufferView(data.Bytes(), target),
+		"componentType": componentType,
+		"count":         count,
+		"type":          accessorType,
+	}
+	for key, value := range extra {
+		accessor[key] = value
+	}
+	w.accessors = append(w.accessors, accessor)
+	return len(w.accessors) - 1
+}
+
+// material maps a Material onto the metallic-roughness model. Materials that
+// didn't come from glTF get a roughness derived from their Shininess.
+func (w *gltfWriter) material(material *lookdev.Material, name string) (map[string]any, error) {
+	kd := material.DiffuseColor
+	alpha := kd.A * (1 - material.Transparency)
+	roughness := material.Roughness
+	if roughness == 0 && material.MetallicRoughnessTexture == nil {
+		// Inverse of the shininess the glTF reader derives from roughness
+		roughness = math.Sqrt(math.Sqrt(2 / (math.Max(0, material.Shininess) + 2)))
+	}
+	pbr := map[string]any{
+		"baseColorFactor": []float64{float64(kd.R) / 255, float64(kd.G) / 255, float64(kd.B) / 255, alpha},
+		"metallicFactor":  material.Metallic,
+		"roughnessFactor": roughness,
+	}
+	result := map[string]any{"name": name, "pbrMetallicRoughness": pbr}
+
+	textures := []struct {
+		texture *lookdev.Texture
+		set     func(index int)
+	}{
+		{material.DiffuseTexture, func(index int) { pbr["baseColorTexture"] = map[string]int{"index": index} }},
+		{material.MetallicRoughnessTexture, func(index int) { pbr["metallicRoughnessTexture"] = map[string]int{"index": index} }},
+		{material.NormalTexture, func(index int) { result["normalTexture"] = map[string]int{"index": index} }},
+	}
+	for _, t := range textures {
+		if t.texture == nil {
+			continue
+		}
+		index, err := w.texture(t.texture)
+		if err != nil {
+			return nil, fmt.Errorf("material %s: %v", name, err)
+		}
+		t.set(index)
+	}
+
+	switch {
+	case material.IsAlphaTested():
+		result["alphaMode"] = "MASK"
+		result["alphaCutoff"] = material.AlphaCutoff
+	case material.IsTransparent():
+		result["alphaMode"] = "BLEND"
+	}
+	return result, nil
+}
+
+// texture embeds tex in the buffer once and returns its texture index. Files
+// in a format glTF allows are copied as is, anything else is encoded as PNG.
+func (w *gltfWriter) texture(tex *lookdev.Texture) (int, error) {
+	if index, ok := w.textureOf[tex]; ok {
+		return index, nil
+	}
+
+	var data []byte
+	mimeType := "image/png"
+	switch strings.ToLower(filepath.Ext(tex.Path)) {
+	case ".png":
+		data, _ = os.ReadFile(tex.Path)
+	case ".jpg", ".jpeg":
+		data, _ = os.ReadFile(tex.Path)
+		mimeType = "image/jpeg"
+	}
+	if data == nil {
+		// Generated, embedded or since deleted, so encode the texels
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, tex.ToImage()); err != nil {
+			return 0, fmt.Errorf("failed to encode texture: %v", err)
+		}
+		data, mimeType = encoded.Bytes(), "image/png"
+	}
+
+	w.images = append(w.images, map[string]any{"bufferView": w.addBufferView(data, 0), "mimeType": mimeType})
+	w.textures = append(w.textures, map[string]any{"source": len(w.images) - 1})
+	w.textureOf[tex] = len(w.textures) - 1
+	return len(w.textures) - 1, nil
+}
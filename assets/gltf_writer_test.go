@@ -0,0 +1,102 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteGLTFRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		bake bool
+	}{
+		{"gltf as stored", "out.gltf", false},
+		{"gltf baked", "out.gltf", true},
+		{"GLB as stored", "out.glb", false},
+		{"GLB baked", "out.glb", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := loadTestPyramid(t, t.TempDir())
+			placeTestGeometry(src)
+
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := WriteGLTF(src, path, tt.bake); err != nil {
+				t.Fatal(err)
+			}
+			scene, err := LoadGLTF(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(scene.Geometries) != 1 {
+				t.Fatalf("read back %d geometries, want 1", len(scene.Geometries))
+			}
+			got := scene.Geometries[0]
+
+			// Unbaked, the node matrix comes back on the Transform
+			checkRoundTrip(t, got, src, src.Transform.ModelMatrix, 1e-5)
+			if tt.bake && got.Transform.GetMatrix() != nomath.IdentityMatrix() {
+				t.Errorf("baked geometry read back with model matrix %v", got.Transform.GetMatrix())
+			}
+
+			if got.Mesh.VertexCount() != src.Mesh.VertexCount() {
+				t.Errorf("read back %d vertices, wrote %d", got.Mesh.VertexCount(), src.Mesh.VertexCount())
+			}
+		})
+	}
+}
+
+func TestWriteGLTFVertexColors(t *testing.T) {
+	dir := t.TempDir()
+	src, err := LoadPLY(writeTestFile(t, dir, "tri.ply", `ply
+format ascii 1.0
+element vertex 3
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+property uchar alpha
+element face 1
+property list uchar int vertex_indices
+end_header
+0 0 0 255 0 0 255
+1 0 0 0 255 0 128
+0 1 0 0 0 255 0
+3 0 1 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "tri.glb")
+	if err := WriteGLTF(src, path, false); err != nil {
+		t.Fatal(err)
+	}
+	scene, err := LoadGLTF(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := scene.Geometries[0].Mesh
+	want := map[nomath.Vec3]lookdev.ColorRGBA{
+		{X: 0, Y: 0}: {R: 255, A: 1},
+		{X: 1, Y: 0}: {G: 255, A: 128.0 / 255},
+		{X: 0, Y: 1}: {B: 255, A: 0},
+	}
+	for i, p := range got.Positions {
+		if got.Colors[i] != want[p] {
+			t.Errorf("vertex at %v has color %v, want %v", p, got.Colors[i], want[p])
+		}
+	}
+}
+
+func TestWriteGLTFEmpty(t *testing.T) {
+	geom := (&Geometry{}).NewGeometry()
+	if err := WriteGLTF(geom, filepath.Join(t.TempDir(), "empty.glb"), false); err == nil {
+		t.Error("wrote a geometry without triangles")
+	}
+}
@@ -0,0 +1,20 @@
+package assets
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// LoadModel loads an OBJ, STL or PLY file, picking the reader by extension
+func LoadModel(path string) (*Geometry, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".obj":
+		return LoadOBJ(path)
+	case ".stl":
+		return LoadSTL(path)
+	case ".ply":
+		return LoadPLY(path)
+	}
+	return nil, fmt.Errorf("unsupported model format %q", filepath.Ext(path))
+}
@@ -0,0 +1,197 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"fmt"
+)
+
+// Mesh holds a Geometry's vertex data as flat arrays with one entry per
+// distinct vertex. A vertex is a unique combination of position, normal, UV
+// and color, so shared corners are stored, and transformed, only once.
+// Triangles refer to their corners through Triangle.Indices.
+type Mesh struct {
+	Positions  []nomath.Vec3
+	Normals    []nomath.Vec3 // Zero where the vertex has no normal
+	UVs        []nomath.Vec2 // Empty when no vertex has UVs
+	Tangents   []nomath.Vec3 // Empty until CalculateTangents, zero where a vertex has none
+	Bitangents []nomath.Vec3
+	Colors     []lookdev.ColorRGBA // Empty when no vertex has a color
+}
+
+// VertexCount returns the number of distinct vertices
+func (m *Mesh) VertexCount() int {
+	return len(m.Positions)
+}
+
+// meshCorner is a triangle corner as a file stores it: an index into each of
+// the loader's attribute lists, or -1 where the corner has none
+type meshCorner struct {
+	position, normal, uv, color int
+}
+
+// offset returns the corner i entries on from c in every list c indexes, for
+// formats that index all attributes together
+func (c meshCorner) offset(i int) meshCorner {
+	out := meshCorner{position: c.position + i, normal: -1, uv: -1, color: -1}
+	if c.normal >= 0 {
+		out.normal = c.normal + i
+	}
+	if c.uv >= 0 {
+		out.uv = c.uv + i
+	}
+	if c.color >= 0 {
+		out.color = c.color + i
+	}
+	return out
+}
+
+// meshBuilder collects a loader's separately indexed attribute lists and
+// triangles, then turns them into the Geometry's Mesh
+type meshBuilder struct {
+	geom      *Geometry
+	positions []nomath.Vec3
+	normals   []nomath.Vec3
+	uvs       []nomath.Vec2
+	colors    []lookdev.ColorRGBA
+	triangles []*Triangle
+	corners   []meshCorner // Three per triangle
+}
+
+func newMeshBuilder(geom *Geometry) *meshBuilder {
+	return &meshBuilder{geom: geom}
+}
+
+// corner returns a corner with only a position, for formats that index all
+// attributes together or have nothing else
+func corner(position int) meshCorner {
+	return meshCorner{position: position, normal: -1, uv: -1, color: -1}
+}
+
+// addTriangle adds a triangle, its indices are filled in by build
+func (b *meshBuilder) addTriangle(material *lookdev.Material, c0, c1, c2 meshCorner) *Triangle {
+	tri := NewTriangle(b.geom, material, 0, 0, 0)
+	b.triangles = append(b.triangles, tri)
+	b.corners = append(b.corners, c0, c1, c2)
+	return tri
+}
+
+// build replaces the Geometry's triangles and Mesh with the collected ones,
+// giving each distinct corner one vertex
+func (b *meshBuilder) build() {
+	hasUVs, hasColors := false, false
+	for _, c := range b.corners {
+		hasUVs = hasUVs || c.uv >= 0
+		hasColors = hasColors || c.color >= 0
+	}
+
+	mesh := &Mesh{}
+	index := make(map[meshCorner]uint32)
+	for t, tri := range b.triangles {
+		for i := 0; i < 3; i++ {
+			c := b.corners[t*3+i]
+			idx, ok := index[c]
+			if !ok {
+				idx = uint32(len(mesh.Positions))
+				index[c] = idx
+				b.addVertex(mesh, c, hasUVs, hasColors)
+			}
+			tri.Indices[i] = idx
+		}
+	}
+	b.geom.Triangles = b.triangles
+	b.geom.Mesh = mesh
+}
+
+func (b *meshBuilder) addVertex(mesh *Mesh, c meshCorner, hasUVs, hasColors bool) {
+	mesh.Positions = append(mesh.Positions, b.positions[c.position])
+
+	var normal nomath.Vec3
+	if c.normal >= 0 {
+		normal = b.normals[c.normal]
+	}
+	mesh.Normals = append(mesh.Normals, normal)
+
+	if hasUVs {
+		var uv nomath.Vec2
+		if c.uv >= 0 {
+			uv = b.uvs[c.uv]
+		}
+		mesh.UVs = append(mesh.UVs, uv)
+	}
+	if hasColors {
+		color := lookdev.ColorRGBA{R: 255, G: 255, B: 255, A: 1}
+		if c.color >= 0 {
+			color = b.colors[c.color]
+		}
+		mesh.Colors = append(mesh.Colors, color)
+	}
+}
+
+// extract copies the vertices the triangles use into a new Mesh and points
+// the triangles at it, for splitting a Geometry into parts
+func (m *Mesh) extract(triangles []*Triangle) *Mesh {
+	out := &Mesh{}
+	remap := make(map[uint32]uint32)
+	for _, tri := range triangles {
+		for i, idx := range tri.Indices {
+			n, ok := remap[idx]
+			if !ok {
+				n = uint32(len(out.Positions))
+				remap[idx] = n
+				out.Positions = append(out.Positions, m.Positions[idx])
+				out.Normals = append(out.Normals, m.Normals[idx])
+				if len(m.UVs) > 0 {
+					out.UVs = append(out.UVs, m.UVs[idx])
+				}
+				if len(m.Tangents) > 0 {
+					out.Tangents = append(out.Tangents, m.Tangents[idx])
+					out.Bitangents = append(out.Bitangents, m.Bitangents[idx])
+				}
+				if len(m.Colors) > 0 {
+					out.Colors = append(out.Colors, m.Colors[idx])
+				}
+			}
+			tri.Indices[i] = n
+		}
+	}
+	return out
+}
+
+// Validate checks that the Mesh's arrays line up and that every triangle
+// belongs to g and only uses vertices its Mesh has
+func (g *Geometry) Validate() error {
+	if g.Mesh == nil {
+		return fmt.Errorf("%s has no mesh", g.Name)
+	}
+	m := g.Mesh
+	count := len(m.Positions)
+	attributes := []struct {
+		name     string
+		length   int
+		optional bool
+	}{
+		{"normals", len(m.Normals), false},
+		{"UVs", len(m.UVs), true},
+		{"tangents", len(m.Tangents), true},
+		{"bitangents", len(m.Bitangents), len(m.Tangents) == 0},
+		{"colors", len(m.Colors), true},
+	}
+	for _, a := range attributes {
+		if a.length != count && !(a.optional && a.length == 0) {
+			return fmt.Errorf("%s has %d %s for %d vertices", g.Name, a.length, a.name, count)
+		}
+	}
+
+	for i, tri := range g.Triangles {
+		if tri.Parent != g {
+			return fmt.Errorf("%s: triangle %d belongs to another geometry", g.Name, i)
+		}
+		for _, idx := range tri.Indices {
+			if int(idx) >= count {
+				return fmt.Errorf("%s: triangle %d uses vertex %d of %d", g.Name, i, idx, count)
+			}
+		}
+	}
+	return nil
+}
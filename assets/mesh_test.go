@@ -0,0 +1,124 @@
+package assets
+
+import (
+	"GopherEngine/nomath"
+	"strings"
+	"testing"
+)
+
+func TestMeshBuilderSharesCorners(t *testing.T) {
+	// Two quads sharing an edge: the corners on it share a vertex where
+	// the UVs match and get one each where they differ
+	path := writeTestFile(t, t.TempDir(), "strip.obj", `v 0 0 0
+v 1 0 0
+v 2 0 0
+v 0 1 0
+v 1 1 0
+v 2 1 0
+vt 0 0
+vt 1 0
+vt 0 1
+vt 1 1
+vn 0 0 1
+f 1/1/1 2/2/1 5/4/1 4/3/1
+f 2/2/1 3/1/1 6/3/1 5/1/1
+`)
+	geom, err := LoadOBJ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := geom.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(geom.Triangles) != 4 {
+		t.Fatalf("got %d triangles, want 4", len(geom.Triangles))
+	}
+	// Corner 2/2/1 is shared, 5/4/1 and 5/1/1 differ by UV
+	if got := geom.Mesh.VertexCount(); got != 7 {
+		t.Errorf("got %d vertices, want 7", got)
+	}
+}
+
+func TestCalculateNormalsAcrossSeams(t *testing.T) {
+	// The corners along the ridge at x = 1 are split by their UVs, but are
+	// smoothed over both slopes all the same
+	path := writeTestFile(t, t.TempDir(), "roof.obj", `v 0 0 0
+v 1 0 1
+v 2 0 0
+v 0 1 0
+v 1 1 1
+v 2 1 0
+vt 0 0
+vt 1 0
+vt 0 1
+vt 1 1
+f 1/1 2/2 5/4 4/3
+f 2/1 3/2 6/4 5/3
+`)
+	geom, err := LoadOBJ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := geom.Mesh.VertexCount(); got != 8 {
+		t.Fatalf("got %d vertices, want the ridge split into 4", got)
+	}
+
+	ridge := make(map[nomath.Vec3]nomath.Vec3)
+	for i, p := range geom.Mesh.Positions {
+		normal := geom.Mesh.Normals[i]
+		if p.X != 1 {
+			if normal.X == 0 {
+				t.Errorf("vertex at %v has normal %v, want the slope's", p, normal)
+			}
+			continue
+		}
+		if normal.Z < 0.9 || normal.Length() < 0.999 {
+			t.Errorf("ridge vertex at %v has normal %v, want it pointing up", p, normal)
+		}
+		if seen, ok := ridge[p]; ok && seen != normal {
+			t.Errorf("ridge vertices at %v have normals %v and %v", p, seen, normal)
+		}
+		ridge[p] = normal
+	}
+}
+
+func TestGeometryValidate(t *testing.T) {
+	build := func() *Geometry {
+		geom := (&Geometry{}).NewGeometry()
+		geom.Name = "tri"
+		geom.Mesh.Positions = []nomath.Vec3{{}, {X: 1}, {Y: 1}}
+		geom.Mesh.Normals = make([]nomath.Vec3, 3)
+		geom.Triangles = append(geom.Triangles, NewTriangle(geom, geom.Material, 0, 1, 2))
+		return geom
+	}
+	tests := []struct {
+		name   string
+		change func(geom *Geometry)
+		want   string
+	}{
+		{"valid", func(geom *Geometry) {}, ""},
+		{"valid with UVs", func(geom *Geometry) { geom.Mesh.UVs = make([]nomath.Vec2, 3) }, ""},
+		{"no mesh", func(geom *Geometry) { geom.Mesh = nil }, "tri has no mesh"},
+		{"short normals", func(geom *Geometry) { geom.Mesh.Normals = geom.Mesh.Normals[:2] }, "tri has 2 normals for 3 vertices"},
+		{"short UVs", func(geom *Geometry) { geom.Mesh.UVs = make([]nomath.Vec2, 1) }, "tri has 1 UVs for 3 vertices"},
+		{"tangents without bitangents", func(geom *Geometry) { geom.Mesh.Tangents = make([]nomath.Vec3, 3) }, "tri has 0 bitangents for 3 vertices"},
+		{"index out of range", func(geom *Geometry) { geom.Triangles[0].Indices[2] = 3 }, "tri: triangle 0 uses vertex 3 of 3"},
+		{"foreign triangle", func(geom *Geometry) { geom.Triangles[0].Parent = build() }, "tri: triangle 0 belongs to another geometry"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geom := build()
+			tt.change(geom)
+			err := geom.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("got error %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"bufio"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadMTL loads a Wavefront material library and returns its materials by
+// name. Texture paths are resolved against baseDir, normally the directory of
+// the OBJ file that references the library.
+func LoadMTL(filename, baseDir string) (map[string]*lookdev.Material, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	materials := make(map[string]*lookdev.Material)
+	var current *lookdev.Material
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		prefix := fields[0]
+		data := fields[1:]
+
+		if prefix == "newmtl" {
+			if len(data) == 0 {
+				return nil, fmt.Errorf("line %d: newmtl needs a name", lineNum)
+			}
+			name := strings.Join(data, " ")
+			current = lookdev.NewMaterial(name)
+			materials[name] = current
+			continue
+		}
+		if current == nil {
+			continue // Statements before the first newmtl have nothing to apply to
+		}
+
+		switch prefix {
+		case "Kd": // Diffuse color
+			color, err := parseMTLColor(data)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid Kd: %v", lineNum, err)
+			}
+			color.A = current.DiffuseColor.A
+			current.DiffuseColor = color
+
+		case "Ks": // Specular color
+			color, err := parseMTLColor(data)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid Ks: %v", lineNum, err)
+			}
+			current.SpecularColor = color
+
+		case "Ns": // Specular exponent
+			ns, err := parseMTLFloat(data)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid Ns: %v", lineNum, err)
+			}
+			current.Shininess = ns
+
+		case "d": // Dissolve, 1 is opaque
+			d, err := parseMTLFloat(data)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid d: %v", lineNum, err)
+			}
+			current.Transparency = 1 - d
+
+		case "Tr": // Transparency, the inverse of d
+			tr, err := parseMTLFloat(data)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid Tr: %v", lineNum, err)
+			}
+			current.Transparency = tr
+
+		case "map_Kd", "map_Ks", "map_Bump", "map_bump", "bump", "norm", "map_d":
+			path := mapFilename(data)
+			if path == "" {
+				return nil, fmt.Errorf("line %d: %s needs a texture file", lineNum, prefix)
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(baseDir, path)
+			}
+			// A missing texture only loses that map, like a missing mtllib
+			tex, err := lookdev.LoadTexture(path)
+			if err != nil {
+				log.Printf("Warning: %s line %d: failed to load texture: %v", filename, lineNum, err)
+				continue
+			}
+
+			switch prefix {
+			case "map_Kd":
+				current.DiffuseTexture = tex
+			case "map_Ks":
+				current.SpecularTexture = tex
+			case "map_d":
+				current.TransparencyTexture = tex
+			default:
+				current.NormalTexture = tex
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading MTL file: %v", err)
+	}
+
+	return materials, nil
+}
+
+func parseMTLFloat(data []string) (float64, error) {
+	if len(data) == 0 {
+		return 0, fmt.Errorf("missing value")
+	}
+	return strconv.ParseFloat(data[0], 64)
+}
+
+// parseMTLColor parses "r g b" in [0, 1]; a single value is used for all three
+func parseMTLColor(data []string) (lookdev.ColorRGBA, error) {
+	if len(data) == 0 {
+		return lookdev.ColorRGBA{}, fmt.Errorf("missing color")
+	}
+	var rgb [3]float64
+	for i := range rgb {
+		field := data[0]
+		if i < len(data) {
+			field = data[i]
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return lookdev.ColorRGBA{}, err
+		}
+		rgb[i] = math.Max(0, math.Min(1, value))
+	}
+	return lookdev.ColorRGBA{
+		R: uint8(math.Round(rgb[0] * 255)),
+		G: uint8(math.Round(rgb[1] * 255)),
+		B: uint8(math.Round(rgb[2] * 255)),
+		A: 1.0,
+	}, nil
+}
+
+// mapFilename skips the options of a texture map statement (such as
+// "-bm 0.5" or "-s 1 1 1") and returns the file name, which may contain spaces
+func mapFilename(data []string) string {
+	i := 0
+	for i < len(data) && strings.HasPrefix(data[i], "-") {
+		option := data[i]
+		i++
+		if option == "-imfchan" || option == "-type" {
+			i++ // Takes a single word
+			continue
+		}
+		for i < len(data) && isMTLOptionValue(data[i]) {
+			i++
+		}
+	}
+	if i >= len(data) {
+		return ""
+	}
+	return strings.Join(data[i:], " ")
+}
+
+func isMTLOptionValue(field string) bool {
+	if field == "on" || field == "off" {
+		return true
+	}
+	_, err := strconv.ParseFloat(field, 64)
+	return err == nil
+}
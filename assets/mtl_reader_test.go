@@ -0,0 +1,130 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestPNG writes a 2x2 PNG filled with c to name in dir
+func writeTestPNG(t *testing.T, dir, name string, c color.RGBA) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMTL(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPNG(t, dir, "red brick.png", color.RGBA{R: 255, A: 255})
+	writeTestPNG(t, dir, "normal.png", color.RGBA{R: 128, G: 128, B: 255, A: 255})
+	path := writeTestFile(t, dir, "test.mtl", `# Materials
+Kd 0 0 0
+
+newmtl brick wall
+Kd 1 0.5 0
+Ks 0.2
+Ns 96
+d 0.75
+map_Kd -s 1 1 1 -bm 0.5 red brick.png
+map_Bump -bm 2 normal.png
+
+newmtl glass
+Tr 0.9
+map_Ks missing.png
+`)
+
+	materials, err := LoadMTL(path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(materials) != 2 {
+		t.Fatalf("got %d materials, want 2", len(materials))
+	}
+
+	brick := materials["brick wall"]
+	if brick == nil {
+		t.Fatal("material \"brick wall\" missing")
+	}
+	if want := (lookdev.ColorRGBA{R: 255, G: 128, B: 0, A: 1}); brick.DiffuseColor != want {
+		t.Errorf("Kd = %v, want %v", brick.DiffuseColor, want)
+	}
+	if want := (lookdev.ColorRGBA{R: 51, G: 51, B: 51, A: 1}); brick.SpecularColor != want {
+		t.Errorf("Ks = %v, want %v", brick.SpecularColor, want)
+	}
+	if brick.Shininess != 96 {
+		t.Errorf("Ns = %v, want 96", brick.Shininess)
+	}
+	if brick.Transparency != 0.25 {
+		t.Errorf("transparency from d = %v, want 0.25", brick.Transparency)
+	}
+	if brick.DiffuseTexture == nil || brick.NormalTexture == nil {
+		t.Errorf("diffuse and normal maps not loaded: %v, %v", brick.DiffuseTexture, brick.NormalTexture)
+	}
+
+	// A missing texture only loses that map
+	glass := materials["glass"]
+	if glass == nil {
+		t.Fatal("material \"glass\" missing")
+	}
+	if glass.Transparency != 0.9 {
+		t.Errorf("transparency from Tr = %v, want 0.9", glass.Transparency)
+	}
+	if glass.SpecularTexture != nil {
+		t.Errorf("missing specular map loaded as %v", glass.SpecularTexture)
+	}
+}
+
+func TestLoadMTLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unnamed material", "newmtl\n", "line 1: newmtl needs a name"},
+		{"bad color", "newmtl a\nKd red\n", "line 2: invalid Kd"},
+		{"missing value", "newmtl a\n\nNs\n", "line 3: invalid Ns"},
+		{"map without file", "newmtl a\nmap_Kd -bm 0.5\n", "line 2: map_Kd needs a texture file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			_, err := LoadMTL(writeTestFile(t, dir, "test.mtl", tt.content), dir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapFilename(t *testing.T) {
+	tests := []struct {
+		data []string
+		want string
+	}{
+		{[]string{"brick.png"}, "brick.png"},
+		{[]string{"-bm", "0.5", "normal.png"}, "normal.png"},
+		{[]string{"-s", "1", "2", "3", "-clamp", "on", "my", "texture.png"}, "my texture.png"},
+		{[]string{"-imfchan", "r", "-type", "sphere", "height.png"}, "height.png"},
+		{[]string{"-bm", "0.5"}, ""},
+	}
+	for _, tt := range tests {
+		if got := mapFilename(tt.data); got != tt.want {
+			t.Errorf("mapFilename(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
@@ -5,40 +5,63 @@ import (
 	"GopherEngine/nomath"
 	"bufio"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
+// objGroup is the run of faces under a g or o statement
+type objGroup struct {
+	name      string
+	triangles []*Triangle
+}
+
 // LoadOBJ loads a Wavefront OBJ file and returns a Geometry object
-func LoadOBJ(filepath string) (*Geometry, error) {
-	file, err := os.Open(filepath)
+func LoadOBJ(objPath string) (*Geometry, error) {
+	geom, _, err := loadOBJ(objPath)
 	if err != nil {
 		return nil, err
 	}
+	return geom, nil
+}
+
+// loadOBJ parses the file into a single Geometry and also reports which
+// triangles belong to which group or object
+func loadOBJ(objPath string) (*Geometry, []*objGroup, error) {
+	file, err := os.Open(objPath)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer file.Close()
 
 	// Create new geometry with default material
-	geomName := strings.TrimSuffix(filepath, ".obj")
-	if geomName == filepath { // If no .obj extension was found
-		geomName = filepath // Use full path as name
+	geomName := strings.TrimSuffix(objPath, ".obj")
+	if geomName == objPath { // If no .obj extension was found
+		geomName = objPath // Use full path as name
 	}
 
 	geom := &Geometry{
 		Name:        geomName,
 		Transform:   nomath.NewTransform(),
-		Vertices:    make([]*nomath.Vec3, 0),
-		Normals:     make([]*nomath.Vec3, 0),
-		UVs:         make([]*nomath.Vec2, 0),
 		Triangles:   make([]*Triangle, 0),
 		BoundingBox: nomath.NewBoundingBox(),
 		Material:    lookdev.NewMaterial(geomName + "_material"),
+
+		CastShadows:    true,
+		ReceiveShadows: true,
 	}
 
-	// Temporary storage for OBJ data
-	var vertices []*nomath.Vec3
-	var texCoords []*nomath.Vec2
-	var normals []*nomath.Vec3
+	// OBJ data, indexed separately until the mesh is built
+	b := newMeshBuilder(geom)
+	material := geom.Material // Set by usemtl
+	missingNormals := false   // Whether any face came without normals
+
+	// Faces before any g or o statement belong to a group named after the file
+	var groups []*objGroup
+	groupName := filepath.Base(geomName)
+	var group *objGroup
 
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
@@ -61,97 +84,126 @@ func LoadOBJ(filepath string) (*Geometry, error) {
 		switch prefix {
 		case "v": // Vertex position
 			if len(data) < 3 {
-				return nil, fmt.Errorf("line %d: vertex needs at least 3 coordinates", lineNum)
+				return nil, nil, fmt.Errorf("line %d: vertex needs at least 3 coordinates", lineNum)
 			}
 			x, err := strconv.ParseFloat(data[0], 64)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid vertex X coordinate: %v", lineNum, err)
+				return nil, nil, fmt.Errorf("line %d: invalid vertex X coordinate: %v", lineNum, err)
 			}
 			y, err := strconv.ParseFloat(data[1], 64)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid vertex Y coordinate: %v", lineNum, err)
+				return nil, nil, fmt.Errorf("line %d: invalid vertex Y coordinate: %v", lineNum, err)
 			}
 			z, err := strconv.ParseFloat(data[2], 64)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid vertex Z coordinate: %v", lineNum, err)
+				return nil, nil, fmt.Errorf("line %d: invalid vertex Z coordinate: %v", lineNum, err)
 			}
-			vertex := &nomath.Vec3{X: x, Y: y, Z: z}
-			vertices = append(vertices, vertex)
-			geom.Vertices = append(geom.Vertices, vertex)
+			b.positions = append(b.positions, nomath.Vec3{X: x, Y: y, Z: z})
 
 		case "vt": // Texture coordinate
 			if len(data) < 2 {
-				return nil, fmt.Errorf("line %d: texture coordinate needs at least 2 values", lineNum)
+				return nil, nil, fmt.Errorf("line %d: texture coordinate needs at least 2 values", lineNum)
 			}
 			u, err := strconv.ParseFloat(data[0], 64)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid texture U coordinate: %v", lineNum, err)
+				return nil, nil, fmt.Errorf("line %d: invalid texture U coordinate: %v", lineNum, err)
 			}
 			v, err := strconv.ParseFloat(data[1], 64)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid texture V coordinate: %v", lineNum, err)
+				return nil, nil, fmt.Errorf("line %d: invalid texture V coordinate: %v", lineNum, err)
 			}
-			uv := &nomath.Vec2{U: u, V: v}
-			texCoords = append(texCoords, uv)
-			geom.UVs = append(geom.UVs, uv)
+			b.uvs = append(b.uvs, nomath.Vec2{U: u, V: v})
 
 		case "vn": // Vertex normal
 			if len(data) < 3 {
-				return nil, fmt.Errorf("line %d: normal needs at least 3 coordinates", lineNum)
+				return nil, nil, fmt.Errorf("line %d: normal needs at least 3 coordinates", lineNum)
 			}
 			x, err := strconv.ParseFloat(data[0], 64)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid normal X coordinate: %v", lineNum, err)
+				return nil, nil, fmt.Errorf("line %d: invalid normal X coordinate: %v", lineNum, err)
 			}
 			y, err := strconv.ParseFloat(data[1], 64)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid normal Y coordinate: %v", lineNum, err)
+				return nil, nil, fmt.Errorf("line %d: invalid normal Y coordinate: %v", lineNum, err)
 			}
 			z, err := strconv.ParseFloat(data[2], 64)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid normal Z coordinate: %v", lineNum, err)
+				return nil, nil, fmt.Errorf("line %d: invalid normal Z coordinate: %v", lineNum, err)
+			}
+			b.normals = append(b.normals, nomath.Vec3{X: x, Y: y, Z: z}.Normalize())
+
+		case "mtllib": // Material libraries, relative to the OBJ file
+			for _, name := range data {
+				materials, err := LoadMTL(filepath.Join(filepath.Dir(objPath), name), filepath.Dir(objPath))
+				if os.IsNotExist(err) {
+					// Exporters often reference libraries that weren't shipped
+					log.Printf("Warning: %s line %d: material library %s not found, using the default material", objPath, lineNum, name)
+					continue
+				}
+				if err != nil {
+					return nil, nil, fmt.Errorf("line %d: material library %s: %v", lineNum, name, err)
+				}
+				if geom.Materials == nil {
+					geom.Materials = make(map[string]*lookdev.Material)
+				}
+				for mtlName, m := range materials {
+					geom.Materials[mtlName] = m
+				}
+			}
+
+		case "g", "o": // Group or object for the following faces
+			if len(data) > 0 {
+				groupName = strings.Join(data, " ")
+			}
+			group = nil
+
+		case "usemtl": // Material for the following faces
+			material = geom.Material
+			if m, ok := geom.Materials[strings.Join(data, " ")]; ok {
+				material = m
 			}
-			normal := nomath.Vec3{X: x, Y: y, Z: z}.Normalize()
-			normals = append(normals, &normal)
-			geom.Normals = append(geom.Normals, &normal)
 
 		case "f": // Face
 			if len(data) < 3 {
-				return nil, fmt.Errorf("line %d: face needs at least 3 vertices", lineNum)
+				return nil, nil, fmt.Errorf("line %d: face needs at least 3 vertices", lineNum)
 			}
 
-			var faceIndices []struct {
-				vIdx, tIdx, nIdx int
-			}
+			var faceIndices []meshCorner
 
 			// Parse all vertex data for the face
 			for _, vertex := range data {
 				parts := strings.Split(vertex, "/")
 				if len(parts) == 0 {
-					return nil, fmt.Errorf("line %d: invalid face vertex format", lineNum)
+					return nil, nil, fmt.Errorf("line %d: invalid face vertex format", lineNum)
 				}
 
 				// Parse vertex index (required)
 				vIdx, err := strconv.Atoi(parts[0])
 				if err != nil {
-					return nil, fmt.Errorf("line %d: invalid vertex index: %v", lineNum, err)
+					return nil, nil, fmt.Errorf("line %d: invalid vertex index: %v", lineNum, err)
 				}
 				if vIdx < 0 {
-					vIdx = len(vertices) + vIdx + 1
+					vIdx = len(b.positions) + vIdx + 1
 				}
 				vIdx-- // Convert to 0-based index
+				if vIdx < 0 || vIdx >= len(b.positions) {
+					return nil, nil, fmt.Errorf("line %d: vertex index %s out of range", lineNum, parts[0])
+				}
 
 				// Parse texture coordinate index (optional)
 				tIdx := -1
 				if len(parts) > 1 && parts[1] != "" {
 					tIdx, err = strconv.Atoi(parts[1])
 					if err != nil {
-						return nil, fmt.Errorf("line %d: invalid texture coordinate index: %v", lineNum, err)
+						return nil, nil, fmt.Errorf("line %d: invalid texture coordinate index: %v", lineNum, err)
 					}
 					if tIdx < 0 {
-						tIdx = len(texCoords) + tIdx + 1
+						tIdx = len(b.uvs) + tIdx + 1
 					}
 					tIdx-- // Convert to 0-based index
+					if tIdx < 0 || tIdx >= len(b.uvs) {
+						return nil, nil, fmt.Errorf("line %d: texture coordinate index %s out of range", lineNum, parts[1])
+					}
 				}
 
 				// Parse normal index (optional)
@@ -159,15 +211,18 @@ func LoadOBJ(filepath string) (*Geometry, error) {
 				if len(parts) > 2 && parts[2] != "" {
 					nIdx, err = strconv.Atoi(parts[2])
 					if err != nil {
-						return nil, fmt.Errorf("line %d: invalid normal index: %v", lineNum, err)
+						return nil, nil, fmt.Errorf("line %d: invalid normal index: %v", lineNum, err)
 					}
 					if nIdx < 0 {
-						nIdx = len(normals) + nIdx + 1
+						nIdx = len(b.normals) + nIdx + 1
 					}
 					nIdx-- // Convert to 0-based index
+					if nIdx < 0 || nIdx >= len(b.normals) {
+						return nil, nil, fmt.Errorf("line %d: normal index %s out of range", lineNum, parts[2])
+					}
 				}
 
-				faceIndices = append(faceIndices, struct{ vIdx, tIdx, nIdx int }{vIdx, tIdx, nIdx})
+				faceIndices = append(faceIndices, meshCorner{position: vIdx, normal: nIdx, uv: tIdx, color: -1})
 			}
 
 			// Triangulate polygon (assuming convex)
@@ -176,99 +231,116 @@ func LoadOBJ(filepath string) (*Geometry, error) {
 				v1 := faceIndices[i]
 				v2 := faceIndices[i+1]
 
-				// Create triangle with references to the geometry's vertices/normals/UVs
-				tri := NewTriangle(
-					geom,
-					geom.Material,
-					vertices[v0.vIdx],
-					vertices[v1.vIdx],
-					vertices[v2.vIdx],
-					nil, // Normals will be set below if available
-					nil,
-					nil,
-					nil, // UVs will be set below if available
-					nil,
-					nil,
-				)
-
-				// Set normals if available
-				if v0.nIdx >= 0 && v1.nIdx >= 0 && v2.nIdx >= 0 {
-					tri.N0 = normals[v0.nIdx]
-					tri.N1 = normals[v1.nIdx]
-					tri.N2 = normals[v2.nIdx]
+				// Normals and UVs are only used when all three corners have them
+				if v0.normal < 0 || v1.normal < 0 || v2.normal < 0 {
+					v0.normal, v1.normal, v2.normal = -1, -1, -1
+					missingNormals = true
 				}
-
-				// Set texture coordinates if available
-				if v0.tIdx >= 0 && v1.tIdx >= 0 && v2.tIdx >= 0 {
-					tri.UV0 = texCoords[v0.tIdx]
-					tri.UV1 = texCoords[v1.tIdx]
-					tri.UV2 = texCoords[v2.tIdx]
+				if v0.uv < 0 || v1.uv < 0 || v2.uv < 0 {
+					v0.uv, v1.uv, v2.uv = -1, -1, -1
 				}
+				tri := b.addTriangle(material, v0, v1, v2)
 
-				geom.Triangles = append(geom.Triangles, tri)
+				// Groups are only created once they have faces, which skips the
+				// vertex-only "g default" blocks that Maya writes
+				if group == nil {
+					group = &objGroup{name: groupName}
+					groups = append(groups, group)
+				}
+				group.triangles = append(group.triangles, tri)
 			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading OBJ file: %v", err)
+		return nil, nil, fmt.Errorf("error reading OBJ file: %v", err)
 	}
 
-	// Calculate normals if not provided in file
-	if len(normals) == 0 {
+	b.build()
+
+	// Calculate normals for the faces the file gave none
+	if missingNormals {
 		geom.CalculateNormals()
 	}
+	if len(b.uvs) > 0 {
+		geom.CalculateTangents()
+	}
 
 	// Compute bounding box
 	geom.ComputeBoundingBox()
 
-	return geom, nil
+	return geom, groups, nil
 }
 
-// CalculateNormals computes vertex normals by averaging face normals
-func (g *Geometry) CalculateNormals() {
-	// First pass: calculate face normals and accumulate at vertices
-	vertexNormals := make([]nomath.Vec3, len(g.Vertices))
-	faceNormals := make([]nomath.Vec3, len(g.Triangles))
-
-	for i, tri := range g.Triangles {
-		// Calculate face normal
-		edge1 := tri.V1.Subtract(*tri.V0)
-		edge2 := tri.V2.Subtract(*tri.V0)
-		normal := edge1.Cross(edge2).Normalize()
-		faceNormals[i] = normal
-
-		// Accumulate at each vertex
-		vertexNormals[getVertexIndex(g.Vertices, tri.V0)] = vertexNormals[getVertexIndex(g.Vertices, tri.V0)].Add(normal)
-		vertexNormals[getVertexIndex(g.Vertices, tri.V1)] = vertexNormals[getVertexIndex(g.Vertices, tri.V1)].Add(normal)
-		vertexNormals[getVertexIndex(g.Vertices, tri.V2)] = vertexNormals[getVertexIndex(g.Vertices, tri.V2)].Add(normal)
-	}
-
-	// Second pass: normalize vertex normals and assign to triangles
-	for i := range vertexNormals {
-		vertexNormals[i] = vertexNormals[i].Normalize()
+// LoadOBJGroups loads a Wavefront OBJ file and returns one Geometry per group
+// or object, each with its own Transform, BoundingBox and the subset of
+// vertices its faces use, so parts can be moved and culled separately.
+// Each part's vertices are centred on its bounds, with Transform.Position
+// placing that centre where it was in the file.
+// Files without g or o statements yield a single Geometry.
+func LoadOBJGroups(objPath string) ([]*Geometry, error) {
+	whole, groups, err := loadOBJ(objPath)
+	if err != nil {
+		return nil, err
 	}
 
-	// Assign normals to triangles
-	for _, tri := range g.Triangles {
-		if tri.N0 == nil {
-			tri.N0 = &vertexNormals[getVertexIndex(g.Vertices, tri.V0)]
+	parts := make([]*Geometry, 0, len(groups))
+	for _, group := range groups {
+		part := &Geometry{
+			Name:        whole.Name + "/" + group.name,
+			Transform:   nomath.NewTransform(),
+			Triangles:   group.triangles,
+			BoundingBox: nomath.NewBoundingBox(),
+			Material:    lookdev.NewMaterial(group.name + "_material"),
+			Materials:   whole.Materials,
+
+			CastShadows:    true,
+			ReceiveShadows: true,
 		}
-		if tri.N1 == nil {
-			tri.N1 = &vertexNormals[getVertexIndex(g.Vertices, tri.V1)]
+
+		for _, tri := range group.triangles {
+			// Each part gets its own default material so they can be changed separately
+			tri.Parent = part
+			if tri.Material == whole.Material {
+				tri.Material = part.Material
+			}
 		}
-		if tri.N2 == nil {
-			tri.N2 = &vertexNormals[getVertexIndex(g.Vertices, tri.V2)]
+		part.Mesh = whole.Mesh.extract(group.triangles)
+
+		// Centre the part's own copy of its vertices
+		part.ComputeBoundingBox()
+		centre := part.BoundingBox.Center()
+		for i := range part.Mesh.Positions {
+			part.Mesh.Positions[i] = part.Mesh.Positions[i].Subtract(centre)
 		}
+		part.Transform.SetPosition(centre)
+
+		part.ComputeBoundingBox()
+		parts = append(parts, part)
 	}
+
+	return parts, nil
 }
 
-// Helper function to find index of a vertex pointer in the slice
-func getVertexIndex(vertices []*nomath.Vec3, v *nomath.Vec3) int {
-	for i, vertex := range vertices {
-		if vertex == v {
-			return i
+// CalculateNormals gives every vertex without a normal the average of the
+// face normals around its position
+func (g *Geometry) CalculateNormals() {
+	mesh := g.Mesh
+
+	// First pass: calculate face normals and accumulate at positions
+	positionNormals := make(map[nomath.Vec3]nomath.Vec3, len(mesh.Positions))
+	for _, tri := range g.Triangles {
+		normal := tri.Normal()
+		for _, idx := range tri.Indices {
+			p := mesh.Positions[idx]
+			positionNormals[p] = positionNormals[p].Add(normal)
+		}
+	}
+
+	// Second pass: normalize and assign to the vertices that have none
+	for i, normal := range mesh.Normals {
+		if normal == (nomath.Vec3{}) {
+			mesh.Normals[i] = positionNormals[mesh.Positions[i]].Normalize()
 		}
 	}
-	return -1
 }
@@ -0,0 +1,127 @@
+package assets
+
+import (
+	"GopherEngine/nomath"
+	"strings"
+	"testing"
+)
+
+func TestLoadOBJMaterials(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "colors.mtl", "newmtl red\nKd 1 0 0\n\nnewmtl blue\nKd 0 0 1\n")
+	path := writeTestFile(t, dir, "quad.obj", `mtllib colors.mtl missing.mtl
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+f 1 2 3
+usemtl red
+f 1 3 4
+usemtl blue
+f 3 2 1
+usemtl unknown
+f 4 3 1
+`)
+
+	geom, err := LoadOBJ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(geom.Materials) != 2 {
+		t.Fatalf("got %d materials, want red and blue", len(geom.Materials))
+	}
+	want := []string{geom.Material.Name, "red", "blue", geom.Material.Name}
+	if len(geom.Triangles) != len(want) {
+		t.Fatalf("got %d triangles, want %d", len(geom.Triangles), len(want))
+	}
+	for i, tri := range geom.Triangles {
+		if tri.Material.Name != want[i] {
+			t.Errorf("triangle %d uses %q, want %q", i, tri.Material.Name, want[i])
+		}
+	}
+}
+
+func TestLoadOBJBrokenMaterialLibrary(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "broken.mtl", "newmtl a\nKd red\n")
+	path := writeTestFile(t, dir, "tri.obj", "v 0 0 0\nv 1 0 0\nv 0 1 0\nmtllib broken.mtl\nf 1 2 3\n")
+
+	// Unlike a missing library, one that fails to parse is an error
+	_, err := LoadOBJ(path)
+	if err == nil || !strings.Contains(err.Error(), "line 4: material library broken.mtl") {
+		t.Errorf("got error %v, want the library's error on line 4", err)
+	}
+}
+
+func TestLoadOBJMixedNormals(t *testing.T) {
+	// The first face brings its own normals, the second needs them computed
+	path := writeTestFile(t, t.TempDir(), "mixed.obj", `v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 2 0 0
+v 2 1 0
+vn 0 0 1
+f 1//1 2//1 3//1 4//1
+f 2 5 6 3
+`)
+	geom, err := LoadOBJ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tri := range geom.Triangles {
+		for corner := 0; corner < 3; corner++ {
+			if normal := tri.normal(corner); !vec3Near(normal, nomath.Vec3{Z: 1}, 1e-9) {
+				t.Errorf("triangle %d corner %d has normal %v, want +Z", i, corner, normal)
+			}
+		}
+	}
+}
+
+func TestLoadOBJGroups(t *testing.T) {
+	// Two quads sharing the edge at x = 1
+	path := writeTestFile(t, t.TempDir(), "parts.obj", `v 0 0 0
+v 1 0 0
+v 3 0 0
+v 0 1 0
+v 1 1 0
+v 3 1 0
+g left
+f 1 2 5 4
+g right
+f 2 3 6 5
+`)
+	parts, err := LoadOBJGroups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want 2", len(parts))
+	}
+
+	centres := []nomath.Vec3{{X: 0.5, Y: 0.5}, {X: 2, Y: 0.5}}
+	for i, part := range parts {
+		if err := part.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if part.Mesh.VertexCount() != 4 || len(part.Triangles) != 2 {
+			t.Errorf("%s has %d vertices and %d triangles, want 4 and 2", part.Name, part.Mesh.VertexCount(), len(part.Triangles))
+		}
+		if part.Transform.Position != centres[i] {
+			t.Errorf("%s is at %v, want its centre %v", part.Name, part.Transform.Position, centres[i])
+		}
+		if centre := part.BoundingBox.Center(); centre != (nomath.Vec3{}) {
+			t.Errorf("%s has local bounds centred on %v, want the origin", part.Name, centre)
+		}
+		for _, p := range part.Mesh.Positions {
+			if !part.BoundingBox.Contains(p) {
+				t.Errorf("%s vertex %v lies outside its bounds", part.Name, p)
+			}
+		}
+	}
+
+	// Each part moved its own copy of the shared edge
+	if min := parts[1].BoundingBox.Min.X; min != -1 {
+		t.Errorf("right part starts at %v, want -1", min)
+	}
+}
@@ -0,0 +1,175 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"bufio"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteOBJ writes geom to objPath along with a .mtl library of its materials
+// next to it. With bakeTransform the vertices and normals are written in
+// world space, otherwise as they are stored. Textures loaded from files are
+// referenced where they are, others are written out as PNG files.
+func WriteOBJ(geom *Geometry, objPath string, bakeTransform bool) error {
+	model, normalMatrix, mirrored := exportTransform(geom, bakeTransform)
+	materials, names := exportMaterials(geom)
+
+	base := strings.TrimSuffix(objPath, filepath.Ext(objPath))
+	mtlPath := base + ".mtl"
+	if err := writeMTL(mtlPath, materials, names); err != nil {
+		return err
+	}
+
+	file, err := os.Create(objPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+
+	fmt.Fprintf(w, "# %d triangles\n", len(geom.Triangles))
+	fmt.Fprintf(w, "mtllib %s\n", filepath.Base(mtlPath))
+	fmt.Fprintf(w, "o %s\n", filepath.Base(geom.Name))
+
+	// Number everything in the order the triangles use it, sharing equal values
+	// between vertices. OBJ indices start at 1.
+	vertexIndex := make(map[nomath.Vec3]int)
+	uvIndex := make(map[nomath.Vec2]int)
+	normalIndex := make(map[nomath.Vec3]int)
+	hasUVs := len(geom.Mesh.UVs) > 0
+	for _, tri := range geom.Triangles {
+		for i := 0; i < 3; i++ {
+			if v := tri.vertex(i); vertexIndex[v] == 0 {
+				vertexIndex[v] = len(vertexIndex) + 1
+				p := model.MultiplyVec4(v.ToVec4(1)).ToVec3()
+				fmt.Fprintf(w, "v %g %g %g\n", p.X, p.Y, p.Z)
+			}
+		}
+	}
+	for _, tri := range geom.Triangles {
+		for i := 0; hasUVs && i < 3; i++ {
+			if uv := tri.uv(i); uvIndex[uv] == 0 {
+				uvIndex[uv] = len(uvIndex) + 1
+				fmt.Fprintf(w, "vt %g %g\n", uv.U, uv.V)
+			}
+		}
+	}
+	for _, tri := range geom.Triangles {
+		for i := 0; i < 3; i++ {
+			if n := tri.normal(i); n != (nomath.Vec3{}) && normalIndex[n] == 0 {
+				normalIndex[n] = len(normalIndex) + 1
+				nn := normalMatrix.TransformVec3(n).Normalize()
+				fmt.Fprintf(w, "vn %g %g %g\n", nn.X, nn.Y, nn.Z)
+			}
+		}
+	}
+
+	for _, material := range materials {
+		fmt.Fprintf(w, "usemtl %s\n", names[material])
+		for _, tri := range geom.Triangles {
+			if triangleMaterial(geom, tri) != material {
+				continue
+			}
+			w.WriteString("f")
+			for _, i := range cornerOrder(mirrored) {
+				v, uv, n := vertexIndex[tri.vertex(i)], 0, normalIndex[tri.normal(i)]
+				if hasUVs {
+					uv = uvIndex[tri.uv(i)]
+				}
+				switch {
+				case uv > 0 && n > 0:
+					fmt.Fprintf(w, " %d/%d/%d", v, uv, n)
+				case n > 0:
+					fmt.Fprintf(w, " %d//%d", v, n)
+				case uv > 0:
+					fmt.Fprintf(w, " %d/%d", v, uv)
+				default:
+					fmt.Fprintf(w, " %d", v)
+				}
+			}
+			w.WriteString("\n")
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing OBJ file: %v", err)
+	}
+	return file.Close()
+}
+
+func writeMTL(mtlPath string, materials []*lookdev.Material, names map[*lookdev.Material]string) error {
+	file, err := os.Create(mtlPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+
+	dir := filepath.Dir(mtlPath)
+	base := strings.TrimSuffix(filepath.Base(mtlPath), filepath.Ext(mtlPath))
+	for _, material := range materials {
+		name := names[material]
+		kd, ks := material.DiffuseColor, material.SpecularColor
+		fmt.Fprintf(w, "newmtl %s\n", name)
+		fmt.Fprintf(w, "Kd %g %g %g\n", float64(kd.R)/255, float64(kd.G)/255, float64(kd.B)/255)
+		fmt.Fprintf(w, "Ks %g %g %g\n", float64(ks.R)/255, float64(ks.G)/255, float64(ks.B)/255)
+		fmt.Fprintf(w, "Ns %g\n", material.Shininess)
+		fmt.Fprintf(w, "d %g\n", 1-material.Transparency)
+
+		maps := []struct {
+			statement string
+			texture   *lookdev.Texture
+		}{
+			{"map_Kd", material.DiffuseTexture},
+			{"map_Ks", material.SpecularTexture},
+			{"map_Bump", material.NormalTexture},
+			{"map_d", material.TransparencyTexture},
+		}
+		for _, m := range maps {
+			if m.texture == nil {
+				continue
+			}
+			path, err := textureFile(m.texture, dir, fmt.Sprintf("%s_%s_%s.png", base, name, m.statement))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s %s\n", m.statement, path)
+		}
+		w.WriteString("\n")
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing MTL file: %v", err)
+	}
+	return file.Close()
+}
+
+// textureFile returns the path an MTL file in dir should use for tex. Textures
+// that weren't loaded from a file are saved as PNG under fallback first.
+func textureFile(tex *lookdev.Texture, dir, fallback string) (string, error) {
+	if tex.Path != "" {
+		absDir, dirErr := filepath.Abs(dir)
+		absPath, pathErr := filepath.Abs(tex.Path)
+		if dirErr == nil && pathErr == nil {
+			if rel, err := filepath.Rel(absDir, absPath); err == nil {
+				return filepath.ToSlash(rel), nil
+			}
+		}
+		return tex.Path, nil
+	}
+
+	file, err := os.Create(filepath.Join(dir, fallback))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if err := png.Encode(file, tex.ToImage()); err != nil {
+		return "", fmt.Errorf("failed to write texture %s: %v", fallback, err)
+	}
+	return fallback, file.Close()
+}
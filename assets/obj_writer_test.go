@@ -0,0 +1,153 @@
+package assets
+
+import (
+	"GopherEngine/nomath"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadTestPyramid loads a square pyramid with normals and UVs, a textured
+// base and transparent sides, from an OBJ written to dir
+func loadTestPyramid(t *testing.T, dir string) *Geometry {
+	t.Helper()
+	writeTestPNG(t, dir, "stone.png", color.RGBA{R: 90, G: 90, B: 90, A: 255})
+	writeTestFile(t, dir, "pyramid.mtl", `newmtl base
+Kd 1 0 0
+Ns 20
+map_Kd stone.png
+
+newmtl sides
+Kd 0 0.5 1
+d 0.5
+`)
+	path := writeTestFile(t, dir, "pyramid.obj", `mtllib pyramid.mtl
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0.5 0.5 1
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vt 0.5 0.5
+vn 0 0 -1
+vn 0 -1 0.5
+vn 1 0 0.5
+vn 0 1 0.5
+vn -1 0 0.5
+usemtl base
+f 1/1/1 3/3/1 2/2/1
+f 1/1/1 4/4/1 3/3/1
+usemtl sides
+f 1/1/2 2/2/2 5/5/2
+f 2/2/3 3/3/3 5/5/3
+f 3/3/4 4/4/4 5/5/4
+f 4/4/5 1/1/5 5/5/5
+`)
+	geom, err := LoadOBJ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return geom
+}
+
+// placeTestGeometry moves, turns, stretches and mirrors geom. Transform
+// clamps negative scales, so the model matrix is mirrored directly.
+func placeTestGeometry(geom *Geometry) {
+	geom.Transform.SetPosition(nomath.Vec3{X: 3, Z: -2})
+	geom.Transform.SetRotation(nomath.Vec3{Y: 0.5})
+	geom.Transform.SetScale(nomath.Vec3{X: 1, Y: 2, Z: 1})
+	geom.Update()
+	geom.Transform.ModelMatrix = nomath.ScaleMatrix(-1, 1, 1).Multiply(geom.Transform.ModelMatrix)
+}
+
+// checkRoundTrip compares the triangles read back from a file with those
+// written, expecting them to land where model put the originals, with the
+// normals turned to match
+func checkRoundTrip(t *testing.T, got, want *Geometry, model nomath.Mat4, tolerance float64) {
+	t.Helper()
+	if err := got.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Triangles) != len(want.Triangles) {
+		t.Fatalf("read back %d triangles, wrote %d", len(got.Triangles), len(want.Triangles))
+	}
+	normalMatrix := model.Inverse().Transpose()
+	gotModel := got.Transform.GetMatrix()
+	gotNormalMatrix := gotModel.Inverse().Transpose()
+	order := [3]int{0, 1, 2}
+	if model.Determinant3x3() < 0 {
+		order = [3]int{0, 2, 1} // Mirroring has to flip the winding
+	}
+
+	for i, tri := range got.Triangles {
+		src := want.Triangles[i]
+		for j, k := range order {
+			position := model.MultiplyVec4(src.vertex(k).ToVec4(1)).ToVec3()
+			if p := gotModel.MultiplyVec4(tri.vertex(j).ToVec4(1)).ToVec3(); !vec3Near(p, position, tolerance) {
+				t.Errorf("triangle %d corner %d at %v, want %v", i, j, p, position)
+			}
+			normal := normalMatrix.TransformVec3(src.normal(k)).Normalize()
+			if n := gotNormalMatrix.TransformVec3(tri.normal(j)).Normalize(); !vec3Near(n, normal, tolerance) {
+				t.Errorf("triangle %d corner %d normal %v, want %v", i, j, n, normal)
+			}
+			if uv, want := tri.uv(j), src.uv(k); !vec3Near(nomath.Vec3{X: uv.U, Y: uv.V}, nomath.Vec3{X: want.U, Y: want.V}, tolerance) {
+				t.Errorf("triangle %d corner %d UV %v, want %v", i, j, uv, want)
+			}
+		}
+		if tri.Normal().Dot(tri.InterpolatedNormal(1.0/3, 1.0/3, 1.0/3)) <= 0 {
+			t.Errorf("triangle %d is wound against its normals", i)
+		}
+		if tri.Material.Name != src.Material.Name || tri.Material.DiffuseColor.R != src.Material.DiffuseColor.R ||
+			tri.Material.DiffuseColor.G != src.Material.DiffuseColor.G || tri.Material.DiffuseColor.B != src.Material.DiffuseColor.B {
+			t.Errorf("triangle %d material %q %v, want %q %v", i, tri.Material.Name, tri.Material.DiffuseColor, src.Material.Name, src.Material.DiffuseColor)
+		}
+		if tri.Material.IsTransparent() != src.Material.IsTransparent() || (tri.Material.DiffuseTexture == nil) != (src.Material.DiffuseTexture == nil) {
+			t.Errorf("triangle %d material %q lost its transparency or texture", i, tri.Material.Name)
+		}
+	}
+}
+
+func TestWriteOBJRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		bake bool
+	}{
+		{"as stored", false},
+		{"baked", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := loadTestPyramid(t, t.TempDir())
+			placeTestGeometry(src)
+
+			// Written elsewhere, so the texture is referenced by a relative path
+			dir := filepath.Join(t.TempDir(), "export")
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "out.obj")
+			if err := WriteOBJ(src, path, tt.bake); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadOBJ(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			model := nomath.IdentityMatrix()
+			if tt.bake {
+				model = src.Transform.ModelMatrix
+			}
+			checkRoundTrip(t, got, src, model, 1e-9)
+
+			// Shared corners stay shared
+			if got.Mesh.VertexCount() != src.Mesh.VertexCount() {
+				t.Errorf("read back %d vertices, wrote %d", got.Mesh.VertexCount(), src.Mesh.VertexCount())
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// plyProperty is one property line of a PLY header. List properties store a
+// count of countType followed by that many values of valueType.
+type plyProperty struct {
+	name      string
+	valueType string
+	isList    bool
+	countType string
+}
+
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+var plyTypeSizes = map[string]int{
+	"char": 1, "uchar": 1, "int8": 1, "uint8": 1,
+	"short": 2, "ushort": 2, "int16": 2, "uint16": 2,
+	"int": 4, "uint": 4, "int32": 4, "uint32": 4,
+	"float": 4, "float32": 4, "double": 8, "float64": 8,
+}
+
+// plyValues reads the body of a PLY file one value at a time
+type plyValues struct {
+	ascii  bool
+	order  binary.ByteOrder
+	data   []byte
+	pos    int
+	tokens []string
+}
+
+func (r *plyValues) read(valueType string) (float64, error) {
+	if r.ascii {
+		if r.pos >= len(r.tokens) {
+			return 0, fmt.Errorf("unexpected end of data")
+		}
+		token := r.tokens[r.pos]
+		r.pos++
+		return strconv.ParseFloat(token, 64)
+	}
+
+	size := plyTypeSizes[valueType]
+	if r.pos+size > len(r.data) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	b := r.data[r.pos : r.pos+size]
+	r.pos += size
+	switch valueType {
+	case "char", "int8":
+		return float64(int8(b[0])), nil
+	case "uchar", "uint8":
+		return float64(b[0]), nil
+	case "short", "int16":
+		return float64(int16(r.order.Uint16(b))), nil
+	case "ushort", "uint16":
+		return float64(r.order.Uint16(b)), nil
+	case "int", "int32":
+		return float64(int32(r.order.Uint32(b))), nil
+	case "uint", "uint32":
+		return float64(r.order.Uint32(b)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(r.order.Uint32(b))), nil
+	}
+	return math.Float64frombits(r.order.Uint64(b)), nil
+}
+
+// LoadPLY loads an ASCII or binary PLY file and returns a Geometry object.
+// Vertex normals, texture coordinates and colors are read when present;
+// colors end up on the triangle corners and tint the diffuse color.
+func LoadPLY(plyPath string) (*Geometry, error) {
+	data, err := os.ReadFile(plyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, values, err := parsePLYHeader(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PLY file %s: %v", plyPath, err)
+	}
+
+	geomName := strings.TrimSuffix(plyPath, filepath.Ext(plyPath))
+	geom := &Geometry{
+		Name:        geomName,
+		Transform:   nomath.NewTransform(),
+		Triangles:   make([]*Triangle, 0),
+		BoundingBox: nomath.NewBoundingBox(),
+		Material:    lookdev.NewMaterial(geomName + "_material"),
+
+		CastShadows:    true,
+		ReceiveShadows: true,
+	}
+
+	builder := newMeshBuilder(geom)
+	for _, element := range elements {
+		switch element.name {
+		case "vertex":
+			err = readPLYVertices(builder, element, values)
+		case "face":
+			err = readPLYFaces(builder, element, values)
+		default:
+			err = skipPLYElement(element, values)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid PLY file %s: %s: %v", plyPath, element.name, err)
+		}
+	}
+
+	builder.build()
+
+	if len(geom.Mesh.Colors) > 0 {
+		// Vertex colors multiply the diffuse color, so don't darken them
+		geom.Material.DiffuseColor = lookdev.ColorRGBA{R: 255, G: 255, B: 255, A: 1.0}
+	}
+	if len(builder.normals) == 0 {
+		geom.CalculateNormals()
+	}
+	geom.CalculateTangents()
+	geom.ComputeBoundingBox()
+
+	return geom, nil
+}
+
+// parsePLYHeader reads the header and returns its elements along with a
+// reader positioned at the start of the body
+func parsePLYHeader(data []byte) ([]*plyElement, *plyValues, error) {
+	reader := bufio.NewReader(bytes.NewReader(data))
+	values := &plyValues{}
+	var elements []*plyElement
+	offset := 0
+	lineNum := 0
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, nil, fmt.Errorf("header has no end_header")
+		}
+		offset += len(line)
+		lineNum++
+		fields := strings.Fields(line)
+		if lineNum == 1 {
+			if len(fields) != 1 || fields[0] != "ply" {
+				return nil, nil, fmt.Errorf("missing ply magic")
+			}
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "format":
+			if len(fields) < 2 {
+				return nil, nil, fmt.Errorf("line %d: format needs a type", lineNum)
+			}
+			switch fields[1] {
+			case "ascii":
+				values.ascii = true
+			case "binary_little_endian":
+				values.order = binary.LittleEndian
+			case "binary_big_endian":
+				values.order = binary.BigEndian
+			default:
+				return nil, nil, fmt.Errorf("line %d: unknown format %q", lineNum, fields[1])
+			}
+
+		case "element":
+			if len(fields) < 3 {
+				return nil, nil, fmt.Errorf("line %d: element needs a name and count", lineNum)
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 {
+				return nil, nil, fmt.Errorf("line %d: invalid element count %q", lineNum, fields[2])
+			}
+			elements = append(elements, &plyElement{name: fields[1], count: count})
+
+		case "property":
+			if len(elements) == 0 {
+				return nil, nil, fmt.Errorf("line %d: property before any element", lineNum)
+			}
+			var property plyProperty
+			if len(fields) == 5 && fields[1] == "list" {
+				property = plyProperty{name: fields[4], valueType: fields[3], isList: true, countType: fields[2]}
+			} else if len(fields) == 3 {
+				property = plyProperty{name: fields[2], valueType: fields[1]}
+			} else {
+				return nil, nil, fmt.Errorf("line %d: malformed property", lineNum)
+			}
+			if plyTypeSizes[property.valueType] == 0 || (property.isList && plyTypeSizes[property.countType] == 0) {
+				return nil, nil, fmt.Errorf("line %d: unknown property type", lineNum)
+			}
+			element := elements[len(elements)-1]
+			element.properties = append(element.properties, property)
+
+		case "end_header":
+			if !values.ascii && values.order == nil {
+				return nil, nil, fmt.Errorf("missing format")
+			}
+			if values.ascii {
+				values.tokens = strings.Fields(string(data[offset:]))
+			} else {
+				values.data = data[offset:]
+			}
+			return elements, values, nil
+		}
+		// comment and obj_info lines need no handling
+	}
+}
+
+func readPLYVertices(b *meshBuilder, element *plyElement, values *plyValues) error {
+	index := make(map[string]int)
+	for i, property := range element.properties {
+		if !property.isList {
+			index[property.name] = i
+		}
+	}
+	has := func(names ...string) bool {
+		for _, name := range names {
+			if _, ok := index[name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	if !has("x", "y", "z") {
+		return fmt.Errorf("vertices need x, y and z")
+	}
+	hasNormals := has("nx", "ny", "nz")
+	uName, vName := "", ""
+	for _, names := range [][2]string{{"u", "v"}, {"s", "t"}, {"texture_u", "texture_v"}, {"texture_s", "texture_t"}} {
+		if has(names[0], names[1]) {
+			uName, vName = names[0], names[1]
+			break
+		}
+	}
+	colorPrefix := ""
+	if has("diffuse_red", "diffuse_green", "diffuse_blue") {
+		colorPrefix = "diffuse_"
+	}
+	hasColors := has(colorPrefix+"red", colorPrefix+"green", colorPrefix+"blue")
+
+	row := make([]float64, len(element.properties))
+	for i := 0; i < element.count; i++ {
+		if err := readPLYRow(element, values, row); err != nil {
+			return err
+		}
+		value := func(name string) float64 { return row[index[name]] }
+
+		b.positions = append(b.positions, nomath.Vec3{X: value("x"), Y: value("y"), Z: value("z")})
+		if hasNormals {
+			b.normals = append(b.normals, nomath.Vec3{X: value("nx"), Y: value("ny"), Z: value("nz")}.Normalize())
+		}
+		if uName != "" {
+			b.uvs = append(b.uvs, nomath.Vec2{U: value(uName), V: value(vName)})
+		}
+		if hasColors {
+			channel := func(name string) float64 {
+				return plyColorChannel(value(name), element.properties[index[name]].valueType)
+			}
+			color := lookdev.ColorRGBA{
+				R: uint8(math.Round(channel(colorPrefix + "red"))),
+				G: uint8(math.Round(channel(colorPrefix + "green"))),
+				B: uint8(math.Round(channel(colorPrefix + "blue"))),
+				A: 1.0,
+			}
+			if has(colorPrefix + "alpha") {
+				color.A = channel(colorPrefix+"alpha") / 255
+			}
+			b.colors = append(b.colors, color)
+		}
+	}
+	return nil
+}
+
+// plyColorChannel maps a color value to [0, 255] whatever type it was stored as
+func plyColorChannel(value float64, valueType string) float64 {
+	switch valueType {
+	case "float", "float32", "double", "float64":
+		value *= 255
+	case "ushort", "uint16":
+		value /= 257
+	}
+	return math.Max(0, math.Min(255, value))
+}
+
+func readPLYFaces(b *meshBuilder, element *plyElement, values *plyValues) error {
+	listIndex := -1
+	for i, property := range element.properties {
+		if property.isList && (property.name == "vertex_indices" || property.name == "vertex_index") {
+			listIndex = i
+		}
+	}
+	if listIndex < 0 {
+		return fmt.Errorf("faces need a vertex_indices list")
+	}
+
+	vertexCount := len(b.positions)
+	var corners []int
+	for i := 0; i < element.count; i++ {
+		for p, property := range element.properties {
+			if !property.isList {
+				if _, err := values.read(property.valueType); err != nil {
+					return err
+				}
+				continue
+			}
+			count, err := values.read(property.countType)
+			if err != nil {
+				return err
+			}
+			if p == listIndex {
+				corners = corners[:0]
+			}
+			for j := 0; j < int(count); j++ {
+				value, err := values.read(property.valueType)
+				if err != nil {
+					return err
+				}
+				if p == listIndex {
+					index := int(value)
+					if index < 0 || index >= vertexCount {
+						return fmt.Errorf("face %d: vertex index %d out of range", i, index)
+					}
+					corners = append(corners, index)
+				}
+			}
+		}
+
+		// Fan out polygons the same way the OBJ reader does
+		for j := 1; j+1 < len(corners); j++ {
+			b.addTriangle(b.geom.Material, plyCorner(b, corners[0]), plyCorner(b, corners[j]), plyCorner(b, corners[j+1]))
+		}
+	}
+	return nil
+}
+
+// plyCorner returns vertex i with every attribute the file has for all vertices
+func plyCorner(b *meshBuilder, i int) meshCorner {
+	c := corner(i)
+	if len(b.normals) == len(b.positions) {
+		c.normal = i
+	}
+	if len(b.uvs) == len(b.positions) {
+		c.uv = i
+	}
+	if len(b.colors) == len(b.positions) {
+		c.color = i
+	}
+	return c
+}
+
+// readPLYRow reads one element whose properties are all scalars. Lists on
+// vertices are rare, they are skipped and read as 0.
+func readPLYRow(element *plyElement, values *plyValues, row []float64) error {
+	for i, property := range element.properties {
+		if property.isList {
+			if err := skipPLYList(property, values); err != nil {
+				return err
+			}
+			row[i] = 0
+			continue
+		}
+		value, err := values.read(property.valueType)
+		if err != nil {
+			return err
+		}
+		row[i] = value
+	}
+	return nil
+}
+
+func skipPLYElement(element *plyElement, values *plyValues) error {
+	row := make([]float64, len(element.properties))
+	for i := 0; i < element.count; i++ {
+		if err := readPLYRow(element, values, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func skipPLYList(property plyProperty, values *plyValues) error {
+	count, err := values.read(property.countType)
+	if err != nil {
+		return err
+	}
+	for j := 0; j < int(count); j++ {
+		if _, err := values.read(property.valueType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
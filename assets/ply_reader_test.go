@@ -0,0 +1,140 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestLoadPLYASCII(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), "quad.ply", `ply
+format ascii 1.0
+comment a colored quad
+element vertex 4
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+element face 1
+property list uchar int vertex_indices
+element edge 1
+property int vertex1
+property int vertex2
+end_header
+0 0 0 255 0 0
+1 0 0 0 255 0
+1 1 0 0 0 255
+0 1 0 255 255 255
+4 0 1 2 3
+0 2
+`)
+	geom, err := LoadPLY(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := geom.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(geom.Triangles) != 2 || geom.Mesh.VertexCount() != 4 {
+		t.Fatalf("got %d triangles and %d vertices, want 2 and 4", len(geom.Triangles), geom.Mesh.VertexCount())
+	}
+
+	want := map[nomath.Vec3]lookdev.ColorRGBA{
+		{X: 0, Y: 0}: {R: 255, A: 1},
+		{X: 1, Y: 0}: {G: 255, A: 1},
+		{X: 1, Y: 1}: {B: 255, A: 1},
+		{X: 0, Y: 1}: {R: 255, G: 255, B: 255, A: 1},
+	}
+	for i, p := range geom.Mesh.Positions {
+		if geom.Mesh.Colors[i] != want[p] {
+			t.Errorf("vertex at %v has color %v, want %v", p, geom.Mesh.Colors[i], want[p])
+		}
+	}
+	if !geom.Triangles[0].HasVertexColors() {
+		t.Error("triangles don't report their vertex colors")
+	}
+	if geom.Material.DiffuseColor != (lookdev.ColorRGBA{R: 255, G: 255, B: 255, A: 1}) {
+		t.Errorf("diffuse color %v would darken the vertex colors", geom.Material.DiffuseColor)
+	}
+}
+
+func TestLoadPLYBinary(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			format := "binary_little_endian"
+			if order == binary.BigEndian {
+				format = "binary_big_endian"
+			}
+			var buf bytes.Buffer
+			buf.WriteString("ply\nformat " + format + " 1.0\n" +
+				"element vertex 3\n" +
+				"property float x\nproperty float y\nproperty float z\n" +
+				"property float nx\nproperty float ny\nproperty float nz\n" +
+				"property float s\nproperty float t\n" +
+				"property float red\nproperty float green\nproperty float blue\nproperty float alpha\n" +
+				"element face 1\nproperty uchar flags\nproperty list uchar uint vertex_index\n" +
+				"end_header\n")
+			binary.Write(&buf, order, []float32{
+				0, 0, 0, 0, 0, 2, 0, 0, 1, 0.5, 0, 1,
+				1, 0, 0, 0, 0, 2, 1, 0, 1, 0.5, 0, 1,
+				0, 1, 0, 0, 0, 2, 0, 1, 1, 0.5, 0, 0.5,
+			})
+			binary.Write(&buf, order, []uint8{7, 3})
+			binary.Write(&buf, order, []uint32{0, 1, 2})
+
+			geom, err := LoadPLY(writeTestFile(t, t.TempDir(), "tri.ply", buf.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := geom.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if len(geom.Triangles) != 1 || geom.Mesh.VertexCount() != 3 {
+				t.Fatalf("got %d triangles and %d vertices, want 1 and 3", len(geom.Triangles), geom.Mesh.VertexCount())
+			}
+			mesh := geom.Mesh
+			if mesh.Normals[1] != (nomath.Vec3{Z: 1}) {
+				t.Errorf("normal %v, want it normalized to +Z", mesh.Normals[1])
+			}
+			if mesh.UVs[1] != (nomath.Vec2{U: 1, V: 0}) {
+				t.Errorf("UV %v, want (1, 0)", mesh.UVs[1])
+			}
+			if want := (lookdev.ColorRGBA{R: 255, G: 128, B: 0, A: 0.5}); mesh.Colors[2] != want {
+				t.Errorf("float color read as %v, want %v", mesh.Colors[2], want)
+			}
+			if len(mesh.Tangents) != 3 || mesh.Tangents[0] != (nomath.Vec3{X: 1}) {
+				t.Errorf("tangents %v, want +X from the UVs", mesh.Tangents)
+			}
+		})
+	}
+}
+
+func TestLoadPLYErrors(t *testing.T) {
+	header := "ply\nformat ascii 1.0\nelement vertex 3\nproperty float x\nproperty float y\nproperty float z\n" +
+		"element face 1\nproperty list uchar int vertex_indices\nend_header\n"
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"missing magic", "format ascii 1.0\nend_header\n", "missing ply magic"},
+		{"no end_header", "ply\nformat ascii 1.0\n", "header has no end_header"},
+		{"unknown format", "ply\nformat binary_middle_endian 1.0\nend_header\n", "line 2: unknown format"},
+		{"unknown type", "ply\nformat ascii 1.0\nelement vertex 1\nproperty quad x\nend_header\n", "line 4: unknown property type"},
+		{"index out of range", header + "0 0 0\n1 0 0\n0 1 0\n3 0 1 3\n", "face 0: vertex index 3 out of range"},
+		{"short data", header + "0 0 0\n1 0 0\n0 1\n", "vertex: unexpected end of data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadPLY(writeTestFile(t, t.TempDir(), "bad.ply", tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
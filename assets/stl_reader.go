@@ -0,0 +1,158 @@
+package assets
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	stlHeaderSize   = 80
+	stlTriangleSize = 50 // Normal, three vertices and a two byte attribute
+)
+
+// LoadSTL loads a binary or ASCII STL file and returns a Geometry object.
+// STL stores every facet separately, so vertices at the same position are
+// welded together, which lets CalculateNormals produce smooth normals.
+func LoadSTL(stlPath string) (*Geometry, error) {
+	data, err := os.ReadFile(stlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	geomName := strings.TrimSuffix(stlPath, filepath.Ext(stlPath))
+	geom := &Geometry{
+		Name:        geomName,
+		Transform:   nomath.NewTransform(),
+		Triangles:   make([]*Triangle, 0),
+		BoundingBox: nomath.NewBoundingBox(),
+		Material:    lookdev.NewMaterial(geomName + "_material"),
+
+		CastShadows:    true,
+		ReceiveShadows: true,
+	}
+
+	builder := newMeshBuilder(geom)
+	welded := make(map[nomath.Vec3]int)
+	weld := func(position nomath.Vec3) int {
+		if index, ok := welded[position]; ok {
+			return index
+		}
+		index := len(builder.positions)
+		welded[position] = index
+		builder.positions = append(builder.positions, position)
+		return index
+	}
+	addFacet := func(a, b, c nomath.Vec3) {
+		v0, v1, v2 := weld(a), weld(b), weld(c)
+		if v0 == v1 || v1 == v2 || v0 == v2 {
+			return // Collapsed by welding, it would only add a zero normal
+		}
+		builder.addTriangle(geom.Material, corner(v0), corner(v1), corner(v2))
+	}
+
+	if isBinarySTL(data) {
+		err = readBinarySTL(data, addFacet)
+	} else {
+		err = readASCIISTL(data, addFacet)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid STL file %s: %v", stlPath, err)
+	}
+
+	builder.build()
+	geom.CalculateNormals()
+	geom.ComputeBoundingBox()
+
+	return geom, nil
+}
+
+// isBinarySTL tells the formats apart. ASCII files start with "solid", but so
+// do the headers of some binary exporters, so the size is checked as well.
+func isBinarySTL(data []byte) bool {
+	if len(data) < stlHeaderSize+4 {
+		return false
+	}
+	count := int(binary.LittleEndian.Uint32(data[stlHeaderSize:]))
+	expected := stlHeaderSize + 4 + count*stlTriangleSize
+	if !bytes.HasPrefix(bytes.TrimSpace(data[:stlHeaderSize]), []byte("solid")) {
+		return true
+	}
+	return len(data) == expected
+}
+
+func readBinarySTL(data []byte, addFacet func(a, b, c nomath.Vec3)) error {
+	count := int(binary.LittleEndian.Uint32(data[stlHeaderSize:]))
+	if len(data) < stlHeaderSize+4+count*stlTriangleSize {
+		return fmt.Errorf("header declares %d triangles but the file is %d bytes", count, len(data))
+	}
+
+	readVec3 := func(offset int) nomath.Vec3 {
+		return nomath.Vec3{
+			X: float64(math.Float32frombits(binary.LittleEndian.Uint32(data[offset:]))),
+			Y: float64(math.Float32frombits(binary.LittleEndian.Uint32(data[offset+4:]))),
+			Z: float64(math.Float32frombits(binary.LittleEndian.Uint32(data[offset+8:]))),
+		}
+	}
+	for i := 0; i < count; i++ {
+		// Skip the facet normal, CalculateNormals replaces it
+		offset := stlHeaderSize + 4 + i*stlTriangleSize + 12
+		addFacet(readVec3(offset), readVec3(offset+12), readVec3(offset+24))
+	}
+	return nil
+}
+
+func readASCIISTL(data []byte, addFacet func(a, b, c nomath.Vec3)) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	var loop []nomath.Vec3
+
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "outer": // outer loop
+			loop = loop[:0]
+
+		case "vertex":
+			if len(fields) < 4 {
+				return fmt.Errorf("line %d: vertex needs 3 coordinates", lineNum)
+			}
+			var xyz [3]float64
+			for i := range xyz {
+				value, err := strconv.ParseFloat(fields[i+1], 64)
+				if err != nil {
+					return fmt.Errorf("line %d: invalid vertex coordinate: %v", lineNum, err)
+				}
+				xyz[i] = value
+			}
+			loop = append(loop, nomath.Vec3{X: xyz[0], Y: xyz[1], Z: xyz[2]})
+
+		case "endloop":
+			if len(loop) < 3 {
+				return fmt.Errorf("line %d: facet needs at least 3 vertices", lineNum)
+			}
+			// Facets are triangles, but fan out anything larger just in case
+			for i := 1; i+1 < len(loop); i++ {
+				addFacet(loop[0], loop[i], loop[i+1])
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading STL file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package assets
+
+import (
+	"GopherEngine/nomath"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// stlQuad is a unit square in the XY plane as two facets sharing an edge
+var stlQuad = [][3]nomath.Vec3{
+	{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 1, Y: 1, Z: 0}},
+	{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 1, Z: 0}, {X: 0, Y: 1, Z: 0}},
+}
+
+// binarySTL encodes facets as a binary STL with the given header text
+func binarySTL(header string, facets [][3]nomath.Vec3) []byte {
+	var buf bytes.Buffer
+	buf.Write(append([]byte(header), make([]byte, stlHeaderSize-len(header))...))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(facets)))
+	for _, facet := range facets {
+		values := []float32{0, 0, 1} // Facet normal
+		for _, v := range facet {
+			values = append(values, float32(v.X), float32(v.Y), float32(v.Z))
+		}
+		binary.Write(&buf, binary.LittleEndian, values)
+		binary.Write(&buf, binary.LittleEndian, uint16(0))
+	}
+	return buf.Bytes()
+}
+
+func TestLoadSTL(t *testing.T) {
+	ascii := `solid quad
+  facet normal 0 0 1
+    outer loop
+      vertex 0 0 0
+      vertex 1 0 0
+      vertex 1 1 0
+    endloop
+  endfacet
+  facet normal 0 0 1
+    outer loop
+      vertex 0 0 0
+      vertex 1 1 0
+      vertex 0 1 0
+    endloop
+  endfacet
+  facet normal 0 0 1
+    outer loop
+      vertex 0 0 0
+      vertex 1 0 0
+      vertex 1e-0 0 0
+    endloop
+  endfacet
+endsolid quad
+`
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"ascii", ascii},
+		{"binary", string(binarySTL("exported by some tool", stlQuad))},
+		{"binary with a solid header", string(binarySTL("solid quad", stlQuad))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geom, err := LoadSTL(writeTestFile(t, t.TempDir(), "quad.stl", tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := geom.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			// Shared corners are welded, and the facet that collapses is dropped
+			if len(geom.Triangles) != 2 || geom.Mesh.VertexCount() != 4 {
+				t.Fatalf("got %d triangles and %d vertices, want 2 and 4", len(geom.Triangles), geom.Mesh.VertexCount())
+			}
+			for i, normal := range geom.Mesh.Normals {
+				if math.Abs(normal.Z-1) > 1e-9 {
+					t.Errorf("vertex %d normal %v, want +Z", i, normal)
+				}
+			}
+			box := geom.BoundingBox
+			if box.Min != (nomath.Vec3{}) || box.Max != (nomath.Vec3{X: 1, Y: 1}) {
+				t.Errorf("bounding box %v to %v, want the unit square", box.Min, box.Max)
+			}
+		})
+	}
+}
+
+func TestLoadSTLErrors(t *testing.T) {
+	truncated := binarySTL("binary", stlQuad)
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"truncated binary", string(truncated[:len(truncated)-10]), "header declares 2 triangles"},
+		{"bad coordinate", "solid x\nfacet normal 0 0 1\nouter loop\nvertex 0 0 zero\n", "line 4: invalid vertex coordinate"},
+		{"short loop", "solid x\nouter loop\nvertex 0 0 0\nvertex 1 0 0\nendloop\n", "line 5: facet needs at least 3 vertices"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadSTL(writeTestFile(t, t.TempDir(), "bad.stl", tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
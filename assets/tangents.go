@@ -0,0 +1,64 @@
+package assets
+
+import (
+	"GopherEngine/nomath"
+	"math"
+)
+
+// CalculateTangents computes per-vertex tangents and bitangents from positions
+// and UVs for tangent-space normal mapping. Vertices only used by triangles
+// without usable UVs are left without a tangent.
+func (g *Geometry) CalculateTangents() {
+	mesh := g.Mesh
+	if len(mesh.UVs) == 0 {
+		return
+	}
+	mesh.Tangents = make([]nomath.Vec3, len(mesh.Positions))
+	mesh.Bitangents = make([]nomath.Vec3, len(mesh.Positions))
+
+	// First pass: accumulate each face's UV directions at its vertices. Since
+	// vertices are split where normals or UVs differ, tangents are only
+	// averaged across smooth, unbroken UVs.
+	for _, tri := range g.Triangles {
+		v0, uv0, uv1, uv2 := tri.vertex(0), tri.uv(0), tri.uv(1), tri.uv(2)
+		edge1 := tri.vertex(1).Subtract(v0)
+		edge2 := tri.vertex(2).Subtract(v0)
+		du1, dv1 := uv1.U-uv0.U, uv1.V-uv0.V
+		du2, dv2 := uv2.U-uv0.U, uv2.V-uv0.V
+
+		det := du1*dv2 - du2*dv1
+		if math.Abs(det) < 1e-12 {
+			continue // Degenerate or missing UVs
+		}
+		r := 1.0 / det
+		tangent := edge1.Multiply(dv2 * r).Subtract(edge2.Multiply(dv1 * r))
+		bitangent := edge2.Multiply(du1 * r).Subtract(edge1.Multiply(du2 * r))
+
+		for _, idx := range tri.Indices {
+			mesh.Tangents[idx] = mesh.Tangents[idx].Add(tangent)
+			mesh.Bitangents[idx] = mesh.Bitangents[idx].Add(bitangent)
+		}
+	}
+
+	// Second pass: make the frames orthonormal around the vertex normals
+	for i, t := range mesh.Tangents {
+		b := mesh.Bitangents[i]
+		if t == (nomath.Vec3{}) {
+			continue
+		}
+		normal := mesh.Normals[i]
+		if normal == (nomath.Vec3{}) {
+			mesh.Tangents[i] = t.Normalize()
+			mesh.Bitangents[i] = b.Normalize()
+			continue
+		}
+
+		tangent := t.Subtract(normal.Multiply(normal.Dot(t))).Normalize()
+		handedness := 1.0
+		if normal.Cross(tangent).Dot(b) < 0 {
+			handedness = -1.0 // Mirrored UVs
+		}
+		mesh.Tangents[i] = tangent
+		mesh.Bitangents[i] = normal.Cross(tangent).Multiply(handedness)
+	}
+}
@@ -0,0 +1,118 @@
+package assets
+
+import (
+	"GopherEngine/nomath"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestFile writes content to name in dir and returns its path
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func vec3Near(a, b nomath.Vec3, tolerance float64) bool {
+	return a.Subtract(b).Length() <= tolerance
+}
+
+// cornerFrame returns the position, normal, tangent and bitangent at corner
+// i of tri, and whether it has a tangent at all
+func cornerFrame(tri *Triangle, i int) (position, normal, tangent, bitangent nomath.Vec3, ok bool) {
+	mesh := tri.Parent.Mesh
+	idx := tri.Indices[i]
+	if len(mesh.Tangents) == 0 || mesh.Tangents[idx] == (nomath.Vec3{}) {
+		return mesh.Positions[idx], mesh.Normals[idx], nomath.Vec3{}, nomath.Vec3{}, false
+	}
+	return mesh.Positions[idx], mesh.Normals[idx], mesh.Tangents[idx], mesh.Bitangents[idx], true
+}
+
+func TestCalculateTangents(t *testing.T) {
+	// A unit quad facing +Z, textured four ways
+	tests := []struct {
+		name          string
+		uvs, normal   string
+		wantTangent   nomath.Vec3
+		wantBitangent nomath.Vec3
+	}{
+		{"aligned", "vt 0 0\nvt 1 0\nvt 1 1\nvt 0 1\n", "0 0 1", nomath.Vec3{X: 1}, nomath.Vec3{Y: 1}},
+		{"rotated", "vt 0 1\nvt 0 0\nvt 1 0\nvt 1 1\n", "0 0 1", nomath.Vec3{Y: 1}, nomath.Vec3{X: -1}},
+		// A mirrored U keeps the bitangent along +V by flipping the handedness
+		{"mirrored", "vt 1 0\nvt 0 0\nvt 0 1\nvt 1 1\n", "0 0 1", nomath.Vec3{X: -1}, nomath.Vec3{Y: 1}},
+		// Tilted normals bend the frame to stay orthonormal around them
+		{"tilted normals", "vt 0 0\nvt 1 0\nvt 1 1\nvt 0 1\n", "0 0.6 0.8", nomath.Vec3{X: 1}, nomath.Vec3{Y: 0.8, Z: -0.6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, t.TempDir(), "quad.obj", "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\n"+tt.uvs+
+				"vn "+tt.normal+"\nf 1/1/1 2/2/1 3/3/1 4/4/1\n")
+			geom, err := LoadOBJ(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, tri := range geom.Triangles {
+				for corner := 0; corner < 3; corner++ {
+					_, _, tangent, bitangent, ok := cornerFrame(tri, corner)
+					if !ok {
+						t.Fatalf("triangle %d corner %d has no tangent", i, corner)
+					}
+					if !vec3Near(tangent, tt.wantTangent, 1e-9) || !vec3Near(bitangent, tt.wantBitangent, 1e-9) {
+						t.Errorf("triangle %d corner %d has frame %v, %v, want %v, %v", i, corner, tangent, bitangent, tt.wantTangent, tt.wantBitangent)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateTangentsSmoothing(t *testing.T) {
+	// Two faces of a roof meeting at a smooth ridge along Y with continuous
+	// UVs, so the ridge corners share one frame for both slopes
+	path := writeTestFile(t, t.TempDir(), "roof.obj", `v 0 0 0
+v 1 0 1
+v 2 0 0
+v 0 1 0
+v 1 1 1
+v 2 1 0
+vt 0 0
+vt 0.5 0
+vt 1 0
+vt 0 1
+vt 0.5 1
+vt 1 1
+f 1/1 2/2 5/5 4/4
+f 2/2 3/3 6/6 5/5
+`)
+	geom, err := LoadOBJ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ridge := make(map[nomath.Vec3]nomath.Vec3)
+	for i, tri := range geom.Triangles {
+		for corner := 0; corner < 3; corner++ {
+			position, normal, tangent, bitangent, ok := cornerFrame(tri, corner)
+			if !ok {
+				t.Fatalf("triangle %d corner %d has no tangent", i, corner)
+			}
+			if math.Abs(tangent.Dot(normal)) > 1e-9 || math.Abs(bitangent.Dot(normal)) > 1e-9 || math.Abs(tangent.Length()-1) > 1e-9 {
+				t.Errorf("triangle %d corner %d frame %v, %v isn't orthonormal around %v", i, corner, tangent, bitangent, normal)
+			}
+			if !vec3Near(bitangent, nomath.Vec3{Y: 1}, 1e-9) {
+				t.Errorf("triangle %d corner %d has bitangent %v, want +Y", i, corner, bitangent)
+			}
+			if position.X != 1 {
+				continue
+			}
+			if seen, ok := ridge[position]; ok && !vec3Near(seen, tangent, 1e-9) {
+				t.Errorf("ridge corners at %v have tangents %v and %v", position, seen, tangent)
+			}
+			ridge[position] = tangent
+		}
+	}
+}
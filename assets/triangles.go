@@ -10,15 +10,7 @@ type Triangle struct {
 	Parent   *Geometry // Reference to parent geometry
 	Material *lookdev.Material
 
-	V0              *nomath.Vec3 // Vertex positions
-	V1              *nomath.Vec3 // Vertex positions
-	V2              *nomath.Vec3 // Vertex positions
-	N0              *nomath.Vec3 // Vertex normals
-	N1              *nomath.Vec3 // Vertex normals
-	N2              *nomath.Vec3 // Vertex normals
-	UV0             *nomath.Vec2 // Texture coordinates
-	UV1             *nomath.Vec2 // Texture coordinates
-	UV2             *nomath.Vec2 // Texture coordinates
+	Indices         [3]uint32 // Corners in Parent.Mesh
 	DiffuseBuffer   *lookdev.ColorRGBA
 	SpecularBuffer  *lookdev.ColorRGBA
 	AlphaBuffer     float64 // Separate alpha buffer for transparency
@@ -27,50 +19,62 @@ type Triangle struct {
 	WorldNormal     nomath.Vec3 // transformed normal after applying NormalMatrix
 }
 
-func NewTriangle(
-	geometry *Geometry, material *lookdev.Material,
-	v0, v1, v2, n0, n1, n2 *nomath.Vec3,
-	uv0, uv1, uv2 *nomath.Vec2) *Triangle {
+func NewTriangle(geometry *Geometry, material *lookdev.Material, i0, i1, i2 uint32) *Triangle {
 	return &Triangle{
 		Parent:      geometry,
 		Material:    material,
-		V0:          v0,
-		V1:          v1,
-		V2:          v2,
-		N0:          n0,
-		N1:          n1,
-		N2:          n2,
-		UV0:         uv0,
-		UV1:         uv1,
-		UV2:         uv2,
+		Indices:     [3]uint32{i0, i1, i2},
 		BufferCache: false,
 	}
 }
 
+func (t *Triangle) vertex(i int) nomath.Vec3 {
+	return t.Parent.Mesh.Positions[t.Indices[i]]
+}
+
+func (t *Triangle) normal(i int) nomath.Vec3 {
+	return t.Parent.Mesh.Normals[t.Indices[i]]
+}
+
+func (t *Triangle) uv(i int) nomath.Vec2 {
+	return t.Parent.Mesh.UVs[t.Indices[i]]
+}
+
+func (t *Triangle) color(i int) lookdev.ColorRGBA {
+	return t.Parent.Mesh.Colors[t.Indices[i]]
+}
+
 func (t *Triangle) Centroid() nomath.Vec3 {
-	return (*t.V0).Add(*t.V1).Add(*t.V2).Multiply(1.0 / 3.0)
+	return t.vertex(0).Add(t.vertex(1)).Add(t.vertex(2)).Multiply(1.0 / 3.0)
 }
 
 func (t *Triangle) Area() float64 {
-	edge1 := t.V1.Subtract(*t.V0)
-	edge2 := t.V2.Subtract(*t.V0)
+	v0 := t.vertex(0)
+	edge1 := t.vertex(1).Subtract(v0)
+	edge2 := t.vertex(2).Subtract(v0)
 	return edge1.Cross(edge2).Length() * 0.5
 }
 
 func (t *Triangle) Normal() nomath.Vec3 {
-	edge1 := t.V1.Subtract(*t.V0)
-	edge2 := t.V2.Subtract(*t.V0)
+	v0 := t.vertex(0)
+	edge1 := t.vertex(1).Subtract(v0)
+	edge2 := t.vertex(2).Subtract(v0)
 	return edge1.Cross(edge2).Normalize()
 }
 
 func (t *Triangle) InterpolatedNormal(u, v, w float64) nomath.Vec3 {
-	n := t.N0.Multiply(u).Add(t.N1.Multiply(v)).Add(t.N2.Multiply(w))
+	n := t.normal(0).Multiply(u).Add(t.normal(1).Multiply(v)).Add(t.normal(2).Multiply(w))
 	return n.Normalize()
 }
 
+// HasUVs reports whether the mesh has texture coordinates
+func (t *Triangle) HasUVs() bool {
+	return len(t.Parent.Mesh.UVs) > 0
+}
+
 func (t *Triangle) InterpolatedUV(u, v, w float64) nomath.Vec2 {
-	// Default to (0,0) if any UV is missing
-	if t.UV0 == nil || t.UV1 == nil || t.UV2 == nil {
+	// Default to (0,0) if the mesh has no UVs
+	if !t.HasUVs() {
 		return nomath.Vec2{U: 0, V: 0}
 	}
 
@@ -87,9 +91,44 @@ func (t *Triangle) InterpolatedUV(u, v, w float64) nomath.Vec2 {
 		w /= sum
 	}
 
+	uv0, uv1, uv2 := t.uv(0), t.uv(1), t.uv(2)
 	return nomath.Vec2{
-		U: t.UV0.U*u + t.UV1.U*v + t.UV2.U*w,
-		V: t.UV0.V*u + t.UV1.V*v + t.UV2.V*w,
+		U: uv0.U*u + uv1.U*v + uv2.U*w,
+		V: uv0.V*u + uv1.V*v + uv2.V*w,
+	}
+}
+
+// HasTangents reports whether all three corners have a tangent frame for
+// normal mapping
+func (t *Triangle) HasTangents() bool {
+	tangents := t.Parent.Mesh.Tangents
+	if len(tangents) == 0 {
+		return false
+	}
+	for _, idx := range t.Indices {
+		if tangents[idx] == (nomath.Vec3{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasVertexColors reports whether the mesh has vertex colors
+func (t *Triangle) HasVertexColors() bool {
+	return len(t.Parent.Mesh.Colors) > 0
+}
+
+// InterpolatedColor blends the vertex colors at the given barycentric weights
+func (t *Triangle) InterpolatedColor(u, v, w float64) lookdev.ColorRGBA {
+	c0, c1, c2 := t.color(0), t.color(1), t.color(2)
+	channel := func(a, b, c uint8) uint8 {
+		return uint8(clamp(math.Round(float64(a)*u+float64(b)*v+float64(c)*w), 0, 255))
+	}
+	return lookdev.ColorRGBA{
+		R: channel(c0.R, c1.R, c2.R),
+		G: channel(c0.G, c1.G, c2.G),
+		B: channel(c0.B, c1.B, c2.B),
+		A: clamp(c0.A*u+c1.A*v+c2.A*w, 0, 1),
 	}
 }
 
@@ -0,0 +1,265 @@
+// Command gopher-render renders scenes to PNG files without opening a window,
+// for CI machines and render farms that have no display.
+//
+//	gopher-render -scene shots/house.json -out frames/house_%04d.png
+//	gopher-render -obj objs/tree_foliage.obj -texture textures/DB2X2_L01.png \
+//	    -position 0,0,-20 -width 1920 -height 1080 -out tree.png
+//	gopher-render -gltf models/street.glb -ssao -msaa 4 -tonemap aces -post fog,bloom,vignette -out street.png
+//	gopher-render -obj objs/house.obj -frames 48 \
+//	    -camera 0,10,10 -camera-end 20,10,10 -camera-end-rot 0,0.8,0 -out turn.png
+package main
+
+import (
+	"GopherEngine/assets"
+	"GopherEngine/core"
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var shadingModes = map[string]int{
+	"flat":    core.ShadingFlat,
+	"gouraud": core.ShadingGouraud,
+	"phong":   core.ShadingPhong,
+}
+
+// postEffects creates the built-in post effects with their default settings
+var postEffects = map[string]func() core.PostEffect{
+	"fog":      func() core.PostEffect { return core.NewFogEffect() },
+	"vignette": func() core.PostEffect { return core.NewVignetteEffect() },
+	"tonemap":  func() core.PostEffect { return core.NewToneMapEffect() },
+	"gamma":    func() core.PostEffect { return core.NewGammaEffect() },
+	"sharpen":  func() core.PostEffect { return core.NewSharpenEffect() },
+	"bloom":    func() core.PostEffect { return core.NewBloomEffect() },
+	"dof":      func() core.PostEffect { return core.NewDepthOfFieldEffect() },
+}
+
+// listFlag collects repeated string flags in order
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// vecFlag parses "x,y,z" into a Vec3
+type vecFlag struct {
+	Value nomath.Vec3
+	IsSet bool
+}
+
+func (v *vecFlag) String() string {
+	if !v.IsSet {
+		return ""
+	}
+	return fmt.Sprintf("%g,%g,%g", v.Value.X, v.Value.Y, v.Value.Z)
+}
+
+func (v *vecFlag) Set(value string) error {
+	vec, err := parseVec3(value)
+	if err != nil {
+		return err
+	}
+	v.Value = vec
+	v.IsSet = true
+	return nil
+}
+
+func parseVec3(value string) (nomath.Vec3, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return nomath.Vec3{}, fmt.Errorf("expected x,y,z but got %q", value)
+	}
+	var xyz [3]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nomath.Vec3{}, fmt.Errorf("invalid component %q: %v", part, err)
+		}
+		xyz[i] = f
+	}
+	return nomath.Vec3{X: xyz[0], Y: xyz[1], Z: xyz[2]}, nil
+}
+
+func main() {
+	var objs, gltfs, textures, positions listFlag
+	var camPos, camRot, camEndPos, camEndRot vecFlag
+
+	sceneFile := flag.String("scene", "", "JSON scene file to render")
+	flag.Var(&objs, "obj", "OBJ, STL or PLY file to load (repeatable)")
+	flag.Var(&gltfs, "gltf", "glTF or GLB file to load with its cameras and lights (repeatable)")
+	flag.Var(&textures, "texture", "diffuse texture for the -obj at the same position (repeatable)")
+	flag.Var(&positions, "position", "x,y,z position for the -obj at the same position (repeatable)")
+	width := flag.Int("width", 0, "output width in pixels (default 854, or the scene file value)")
+	height := flag.Int("height", 0, "output height in pixels (default 480, or the scene file value)")
+	frames := flag.Int("frames", 0, "number of frames to render along the camera path")
+	out := flag.String("out", "render.png", "output PNG file or printf pattern such as frame_%04d.png")
+	overlays := flag.Bool("overlays", false, "draw the grid and view axes")
+	alphaCutoff := flag.Float64("alpha-cutoff", 0, "cut out -texture texels with less alpha instead of blending them (0 disables)")
+	shading := flag.String("shading", "flat", "shading mode: flat, gouraud or phong")
+	shadows := flag.Bool("shadows", false, "make every directional and spot light cast shadows")
+	ssao := flag.Bool("ssao", false, "darken ambient light in creases with screen-space ambient occlusion")
+	msaa := flag.Int("msaa", 1, "samples per pixel for multisample anti-aliasing: 1 (off), 2, 4 or 8")
+	fxaa := flag.Bool("fxaa", false, "smooth edges with fast approximate anti-aliasing")
+	supersample := flag.Float64("supersample", 1, "render at this multiple of the output size and filter down")
+	toneMapping := flag.String("tonemap", "exposure", "tone mapping operator: exposure, reinhard or aces")
+	exposure := flag.Float64("exposure", 1, "scale the linear frame by this before tone mapping")
+	post := flag.String("post", "", "comma-separated post effects to apply in order: fog, vignette, tonemap, gamma, sharpen, bloom, dof")
+	flag.Var(&camPos, "camera", "camera start position x,y,z")
+	flag.Var(&camRot, "camera-rot", "camera start rotation x,y,z in radians")
+	flag.Var(&camEndPos, "camera-end", "camera end position x,y,z for multi-frame renders")
+	flag.Var(&camEndRot, "camera-end-rot", "camera end rotation x,y,z in radians for multi-frame renders")
+	flag.Parse()
+
+	if *sceneFile == "" && len(objs) == 0 && len(gltfs) == 0 {
+		fmt.Fprintln(os.Stderr, "gopher-render: nothing to render, pass -scene, -obj or -gltf")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var scene *core.Scene
+	var path *core.CameraPath
+	outWidth, outHeight, outFrames := core.SCREEN_WIDTH, core.SCREEN_HEIGHT, 1
+
+	if *sceneFile != "" {
+		var desc *core.SceneFile
+		var err error
+		scene, path, desc, err = core.LoadSceneFile(*sceneFile)
+		if err != nil {
+			log.Fatalf("Failed to load scene: %v", err)
+		}
+		if desc.Width > 0 {
+			outWidth = desc.Width
+		}
+		if desc.Height > 0 {
+			outHeight = desc.Height
+		}
+		if desc.Frames > 0 {
+			outFrames = desc.Frames
+		}
+	} else {
+		scene = core.NewScene()
+	}
+
+	for _, gltfPath := range gltfs {
+		gltf, err := assets.LoadGLTF(gltfPath)
+		if err != nil {
+			log.Fatalf("Failed to load glTF file: %v", err)
+		}
+		scene.AddGLTF(gltf)
+	}
+
+	for i, objPath := range objs {
+		geom, err := assets.LoadModel(objPath)
+		if err != nil {
+			log.Fatalf("Failed to load model: %v", err)
+		}
+		if i < len(textures) && textures[i] != "" {
+			tex, err := lookdev.LoadTexture(textures[i])
+			if err != nil {
+				log.Printf("Warning: Failed to load texture: %v", err)
+			} else {
+				geom.Material.DiffuseTexture = tex
+				geom.Material.AlphaCutoff = *alphaCutoff
+			}
+		}
+		if i < len(positions) {
+			pos, err := parseVec3(positions[i])
+			if err != nil {
+				log.Fatalf("Invalid -position for %s: %v", objPath, err)
+			}
+			geom.Transform.SetPosition(pos)
+		}
+		scene.AddObject(geom)
+	}
+
+	if *width > 0 {
+		outWidth = *width
+	}
+	if *height > 0 {
+		outHeight = *height
+	}
+	if *frames > 0 {
+		outFrames = *frames
+	}
+
+	if camPos.IsSet {
+		scene.Camera.Transform.SetPosition(camPos.Value)
+	}
+	if camRot.IsSet {
+		scene.Camera.Transform.SetRotation(camRot.Value)
+	}
+	if camEndPos.IsSet || camEndRot.IsSet {
+		start := core.CameraKeyframe{Position: scene.Camera.Transform.Position, Rotation: scene.Camera.Transform.Rotation}
+		end := start
+		if camEndPos.IsSet {
+			end.Position = camEndPos.Value
+		}
+		if camEndRot.IsSet {
+			end.Rotation = camEndRot.Value
+		}
+		path = &core.CameraPath{Keyframes: []core.CameraKeyframe{start, end}}
+	}
+
+	shadingMode, ok := shadingModes[*shading]
+	if !ok {
+		log.Fatalf("Unknown shading mode %q", *shading)
+	}
+	scene.Renderer.ShadingMode = shadingMode
+
+	toneMappingMode, ok := core.ParseToneMap(*toneMapping)
+	if !ok {
+		log.Fatalf("Unknown tone mapping operator %q", *toneMapping)
+	}
+	scene.Renderer.ToneMapping = toneMappingMode
+	scene.Renderer.Exposure = *exposure
+
+	if *shadows {
+		for _, light := range scene.Lights {
+			if light.Type != core.LightTypePoint {
+				light.CastShadows = true
+			}
+		}
+	}
+
+	scene.Renderer.SSAOEnabled = *ssao
+	scene.Renderer.MSAASamples = *msaa
+	scene.Renderer.FXAAEnabled = *fxaa
+	scene.Renderer.Supersampling = *supersample
+	if *post != "" {
+		for _, name := range strings.Split(*post, ",") {
+			newEffect, ok := postEffects[strings.TrimSpace(name)]
+			if !ok {
+				log.Fatalf("Unknown post effect %q", name)
+			}
+			scene.Renderer.PostEffects = append(scene.Renderer.PostEffects, newEffect())
+		}
+	}
+
+	scene.Grid.Enabled = *overlays
+	scene.ViewAxes.Enabled = *overlays
+
+	if dir := filepath.Dir(*out); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Fatalf("Failed to create output directory: %v", err)
+		}
+	}
+
+	written, err := scene.RenderSequence(path, outFrames, outWidth, outHeight, *out)
+	if err != nil {
+		log.Fatalf("Render failed: %v", err)
+	}
+	for _, filename := range written {
+		fmt.Println(filename)
+	}
+}
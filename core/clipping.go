@@ -0,0 +1,105 @@
+package core
+
+import "GopherEngine/nomath"
+
+// clipVertex is a clip-space position along with its barycentric weights on
+// the source triangle, so attributes can still be interpolated after clipping.
+type clipVertex struct {
+	Position nomath.Vec4
+	Weights  nomath.Vec3
+}
+
+// clipPlanes are the view frustum in homogeneous clip space. A position is on
+// the inside of a plane when its dot product with it is not negative.
+var clipPlanes = [6]nomath.Vec4{
+	{Z: 1, W: 1},  // Near, z >= -w
+	{Z: -1, W: 1}, // Far, z <= w
+	{X: 1, W: 1},  // Left, x >= -w
+	{X: -1, W: 1}, // Right, x <= w
+	{Y: 1, W: 1},  // Bottom, y >= -w
+	{Y: -1, W: 1}, // Top, y <= w
+}
+
+// Each plane can add at most one vertex to a convex polygon
+const maxClippedVertices = 3 + len(clipPlanes)
+
+func planeDistance(plane, p nomath.Vec4) float64 {
+	return plane.X*p.X + plane.Y*p.Y + plane.Z*p.Z + plane.W*p.W
+}
+
+// outcode has a bit set for every clip plane p is outside of
+func outcode(p nomath.Vec4) uint8 {
+	var code uint8
+	for i, plane := range clipPlanes {
+		if planeDistance(plane, p) < 0 {
+			code |= 1 << i
+		}
+	}
+	return code
+}
+
+func lerpClipVertex(a, b clipVertex, t float64) clipVertex {
+	return clipVertex{
+		Position: a.Position.Add(b.Position.Sub(a.Position).Multiply(t)),
+		Weights:  a.Weights.Add(b.Weights.Subtract(a.Weights).Multiply(t)),
+	}
+}
+
+// clipTriangle clips a triangle against the planes set in planes with the
+// Sutherland–Hodgman algorithm and returns the convex polygon left over,
+// which is empty when nothing of the triangle is inside.
+func clipTriangle(verts [3]clipVertex, planes uint8) []clipVertex {
+	var bufA, bufB [maxClippedVertices]clipVertex
+	src := append(bufA[:0], verts[:]...)
+	dst := bufB[:0]
+
+	for i, plane := range clipPlanes {
+		if planes&(1<<i) == 0 {
+			continue
+		}
+		dst = dst[:0]
+		for j := range src {
+			curr, next := src[j], src[(j+1)%len(src)]
+			dCurr, dNext := planeDistance(plane, curr.Position), planeDistance(plane, next.Position)
+			if dCurr >= 0 {
+				dst = append(dst, curr)
+			}
+			// Where an edge crosses the plane keep the intersection, always
+			// measured from the inside end so the neighbouring triangle
+			// sharing the edge gets exactly the same vertex and no crack
+			switch {
+			case dCurr >= 0 && dNext < 0:
+				dst = append(dst, lerpClipVertex(curr, next, dCurr/(dCurr-dNext)))
+			case dCurr < 0 && dNext >= 0:
+				dst = append(dst, lerpClipVertex(next, curr, dNext/(dNext-dCurr)))
+			}
+		}
+		if len(dst) < 3 {
+			return nil
+		}
+		src, dst = dst, src
+	}
+	return src
+}
+
+// clipLine clips the segment a-b against the frustum. ok is false when none
+// of it is inside.
+func clipLine(a, b nomath.Vec4) (nomath.Vec4, nomath.Vec4, bool) {
+	t0, t1 := 0.0, 1.0
+	for _, plane := range clipPlanes {
+		dA, dB := planeDistance(plane, a), planeDistance(plane, b)
+		switch {
+		case dA < 0 && dB < 0:
+			return a, b, false
+		case dA < 0:
+			t0 = max(t0, dA/(dA-dB))
+		case dB < 0:
+			t1 = min(t1, dA/(dA-dB))
+		}
+	}
+	if t0 > t1 {
+		return a, b, false
+	}
+	delta := b.Sub(a)
+	return a.Add(delta.Multiply(t0)), a.Add(delta.Multiply(t1)), true
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"GopherEngine/nomath"
+	"math"
+	"testing"
+)
+
+func vec4Near(a, b nomath.Vec4) bool {
+	const eps = 1e-9
+	return math.Abs(a.X-b.X) < eps && math.Abs(a.Y-b.Y) < eps && math.Abs(a.Z-b.Z) < eps && math.Abs(a.W-b.W) < eps
+}
+
+func sourceTriangle(a, b, c nomath.Vec4) [3]clipVertex {
+	return [3]clipVertex{
+		{Position: a, Weights: nomath.Vec3{X: 1}},
+		{Position: b, Weights: nomath.Vec3{Y: 1}},
+		{Position: c, Weights: nomath.Vec3{Z: 1}},
+	}
+}
+
+func TestClipTriangle(t *testing.T) {
+	tests := []struct {
+		name  string
+		verts [3]clipVertex
+		want  []nomath.Vec4 // Clipped polygon, nil when nothing is left
+	}{
+		{
+			name:  "inside",
+			verts: sourceTriangle(nomath.Vec4{W: 1}, nomath.Vec4{X: 0.5, W: 1}, nomath.Vec4{Y: 0.5, W: 1}),
+			want:  []nomath.Vec4{{W: 1}, {X: 0.5, W: 1}, {Y: 0.5, W: 1}},
+		},
+		{
+			name:  "crossing right",
+			verts: sourceTriangle(nomath.Vec4{W: 1}, nomath.Vec4{X: 2, W: 1}, nomath.Vec4{Y: 1, W: 1}),
+			want:  []nomath.Vec4{{W: 1}, {X: 1, W: 1}, {X: 1, Y: 0.5, W: 1}, {Y: 1, W: 1}},
+		},
+		{
+			name:  "crossing right and top",
+			verts: sourceTriangle(nomath.Vec4{X: 0.5, Y: 0.5, W: 1}, nomath.Vec4{X: 1.5, Y: 0.5, W: 1}, nomath.Vec4{X: 0.5, Y: 1.3, W: 1}),
+			want:  []nomath.Vec4{{X: 0.5, Y: 0.5, W: 1}, {X: 1, Y: 0.5, W: 1}, {X: 1, Y: 0.9, W: 1}, {X: 0.875, Y: 1, W: 1}, {X: 0.5, Y: 1, W: 1}},
+		},
+		{
+			name:  "behind near plane",
+			verts: sourceTriangle(nomath.Vec4{Z: 0, W: 1}, nomath.Vec4{Z: -3, W: 1}, nomath.Vec4{X: 0.5, Z: -3, W: 1}),
+			want:  []nomath.Vec4{{Z: 0, W: 1}, {Z: -1, W: 1}, {X: 1.0 / 6, Z: -1, W: 1}},
+		},
+		{
+			name:  "outside",
+			verts: sourceTriangle(nomath.Vec4{X: -2, W: 1}, nomath.Vec4{X: -3, W: 1}, nomath.Vec4{X: -2, Y: 0.5, W: 1}),
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planes := outcode(tt.verts[0].Position) | outcode(tt.verts[1].Position) | outcode(tt.verts[2].Position)
+			got := clipTriangle(tt.verts, planes)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d vertices, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, v := range got {
+				if !vec4Near(v.Position, tt.want[i]) {
+					t.Errorf("vertex %d at %v, want %v", i, v.Position, tt.want[i])
+				}
+				// The weights must rebuild the position from the source triangle
+				var p nomath.Vec4
+				for k, w := range [3]float64{v.Weights.X, v.Weights.Y, v.Weights.Z} {
+					p = p.Add(tt.verts[k].Position.Multiply(w))
+				}
+				if !vec4Near(p, v.Position) {
+					t.Errorf("vertex %d weights %v give %v, not %v", i, v.Weights, p, v.Position)
+				}
+			}
+		})
+	}
+}
+
+func TestClipTriangleSharedEdge(t *testing.T) {
+	// Two triangles share the edge a-b, which crosses the right plane, and
+	// walk it in opposite directions. Both must cut it at the same point.
+	a, b := nomath.Vec4{X: 0.3, Y: -0.7, W: 1}, nomath.Vec4{X: 1.9, Y: 0.45, W: 1}
+	first := clipTriangle(sourceTriangle(a, b, nomath.Vec4{Y: 0.9, W: 1}), outcode(b))
+	second := clipTriangle(sourceTriangle(b, a, nomath.Vec4{X: 0.8, Y: -0.95, W: 1}), outcode(b))
+
+	found := 0
+	for _, u := range first {
+		for _, v := range second {
+			if u.Position == v.Position && u.Position.X == 1 {
+				found++
+			}
+		}
+	}
+	if found != 1 {
+		t.Errorf("triangles share %d cut points on the edge, want exactly 1", found)
+	}
+}
+
+func TestClipLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   nomath.Vec4
+		wantOK bool
+		wantA  nomath.Vec4
+		wantB  nomath.Vec4
+	}{
+		{"inside", nomath.Vec4{X: -0.5, W: 1}, nomath.Vec4{X: 0.5, W: 1}, true, nomath.Vec4{X: -0.5, W: 1}, nomath.Vec4{X: 0.5, W: 1}},
+		{"crossing right", nomath.Vec4{W: 1}, nomath.Vec4{X: 2, W: 1}, true, nomath.Vec4{W: 1}, nomath.Vec4{X: 1, W: 1}},
+		{"crossing both sides", nomath.Vec4{Y: -3, W: 1}, nomath.Vec4{Y: 3, W: 1}, true, nomath.Vec4{Y: -1, W: 1}, nomath.Vec4{Y: 1, W: 1}},
+		{"behind near plane", nomath.Vec4{Z: 0, W: 1}, nomath.Vec4{Z: -3, W: 1}, true, nomath.Vec4{Z: 0, W: 1}, nomath.Vec4{Z: -1, W: 1}},
+		{"outside one plane", nomath.Vec4{X: 2, W: 1}, nomath.Vec4{X: 3, Y: 0.5, W: 1}, false, nomath.Vec4{}, nomath.Vec4{}},
+		{"passing a corner", nomath.Vec4{X: -3, W: 1}, nomath.Vec4{Y: 3, W: 1}, false, nomath.Vec4{}, nomath.Vec4{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b, ok := clipLine(tt.a, tt.b)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (!vec4Near(a, tt.wantA) || !vec4Near(b, tt.wantB)) {
+				t.Errorf("got %v-%v, want %v-%v", a, b, tt.wantA, tt.wantB)
+			}
+		})
+	}
+}
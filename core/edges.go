@@ -0,0 +1,54 @@
+package core
+
+import "math"
+
+// Screen positions are snapped to fixed point with this many fractional bits,
+// so adjacent triangles see exactly the same shared edges
+const (
+	subpixelBits = 8
+	subpixelOne  = 1 << subpixelBits
+	subpixelHalf = subpixelOne / 2
+)
+
+// edgeEquation is the half-space function of a directed triangle edge in
+// fixed point. It is positive on the inside of a triangle wound the way
+// projectTriangle arranges them, and is stepped incrementally per pixel.
+type edgeEquation struct {
+	stepX, stepY int64 // Change per pixel to the right and per pixel down
+	x0, y0       int64 // Edge start
+	dx, dy       int64
+	bias         int64 // 0 for top and left edges, -1 for the rest
+}
+
+// newEdgeEquation returns the edge from (x0, y0) to (x1, y1). Screen y points
+// down, so with the winding used here a top edge runs exactly horizontally to
+// the right and a left edge runs up. Pixels centred exactly on an edge only
+// belong to the triangle when it is a top or left edge (the top-left rule),
+// so meshes are drawn without gaps or pixels drawn twice.
+func newEdgeEquation(x0, y0, x1, y1 int64) edgeEquation {
+	dx, dy := x1-x0, y1-y0
+	e := edgeEquation{
+		stepX: -dy * subpixelOne,
+		stepY: dx * subpixelOne,
+		x0:    x0, y0: y0,
+		dx: dx, dy: dy,
+		bias: -1,
+	}
+	if (dy == 0 && dx > 0) || dy < 0 {
+		e.bias = 0
+	}
+	return e
+}
+
+// at evaluates the edge at the centre of pixel (x, y), including the fill
+// rule bias, so a pixel is inside when the value is not negative
+func (e edgeEquation) at(x, y int) int64 {
+	px := int64(x)<<subpixelBits + subpixelHalf
+	py := int64(y)<<subpixelBits + subpixelHalf
+	return e.dx*(py-e.y0) - e.dy*(px-e.x0) + e.bias
+}
+
+// toSubpixel snaps a screen coordinate to fixed point
+func toSubpixel(v float64) int64 {
+	return int64(math.Round(v * subpixelOne))
+}
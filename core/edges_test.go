@@ -0,0 +1,159 @@
+package core
+
+import (
+	"GopherEngine/nomath"
+	"testing"
+)
+
+// coverage projects triangles given in pixel coordinates and counts how many
+// of them cover each pixel centre
+func coverage(r *Renderer3D, triangles [][3]nomath.Vec2) []int {
+	width, height := r.GetWidth(), r.GetHeight()
+	counts := make([]int, width*height)
+	for _, corners := range triangles {
+		var verts [3]clipVertex
+		for i, p := range corners {
+			verts[i].Position = nomath.Vec4{X: p.U/float64(width)*2 - 1, Y: 1 - p.V/float64(height)*2, W: 1}
+		}
+		st, ok := r.projectTriangle(verts, nil)
+		if !ok {
+			continue
+		}
+		for y := st.bounds.Min.Y; y <= st.bounds.Max.Y; y++ {
+			for x := st.bounds.Min.X; x <= st.bounds.Max.X; x++ {
+				if st.edges[0].at(x, y)|st.edges[1].at(x, y)|st.edges[2].at(x, y) >= 0 {
+					counts[y*width+x]++
+				}
+			}
+		}
+	}
+	return counts
+}
+
+// gridTriangles splits the rectangle between corners from and to into cells
+// of two triangles each, alternating the diagonal and winding. Inner vertices
+// are moved by jitter so the shared edges run at odd angles.
+func gridTriangles(from, to nomath.Vec2, cells int, jitter float64) [][3]nomath.Vec2 {
+	point := func(i, j int) nomath.Vec2 {
+		p := nomath.Vec2{
+			U: from.U + (to.U-from.U)*float64(i)/float64(cells),
+			V: from.V + (to.V-from.V)*float64(j)/float64(cells),
+		}
+		if i > 0 && i < cells && j > 0 && j < cells {
+			p.U += jitter * float64((i*7+j*3)%5-2)
+			p.V += jitter * float64((i*3+j*5)%5-2)
+		}
+		return p
+	}
+
+	var triangles [][3]nomath.Vec2
+	for j := 0; j < cells; j++ {
+		for i := 0; i < cells; i++ {
+			a, b, c, d := point(i, j), point(i+1, j), point(i+1, j+1), point(i, j+1)
+			if (i+j)%2 == 0 {
+				triangles = append(triangles, [3]nomath.Vec2{a, b, c}, [3]nomath.Vec2{a, d, c})
+			} else {
+				triangles = append(triangles, [3]nomath.Vec2{a, b, d}, [3]nomath.Vec2{b, c, d})
+			}
+		}
+	}
+	return triangles
+}
+
+// fanTriangles splits the rectangle between corners from and to into triangles
+// around centre, using the corners and edge midpoints
+func fanTriangles(from, to, centre nomath.Vec2) [][3]nomath.Vec2 {
+	mid := nomath.Vec2{U: (from.U + to.U) / 2, V: (from.V + to.V) / 2}
+	ring := []nomath.Vec2{
+		from, {U: mid.U, V: from.V}, {U: to.U, V: from.V}, {U: to.U, V: mid.V},
+		to, {U: mid.U, V: to.V}, {U: from.U, V: to.V}, {U: from.U, V: mid.V},
+	}
+	var triangles [][3]nomath.Vec2
+	for i := range ring {
+		triangles = append(triangles, [3]nomath.Vec2{centre, ring[i], ring[(i+1)%len(ring)]})
+	}
+	return triangles
+}
+
+func TestEdgeEquationWatertight(t *testing.T) {
+	tests := []struct {
+		name      string
+		min, max  nomath.Vec2
+		triangles [][3]nomath.Vec2
+	}{
+		{
+			name: "quad on pixel centres",
+			min:  nomath.Vec2{U: 10.5, V: 10.5}, max: nomath.Vec2{U: 20.5, V: 30.5},
+			triangles: [][3]nomath.Vec2{
+				{{U: 10.5, V: 10.5}, {U: 20.5, V: 10.5}, {U: 20.5, V: 30.5}},
+				{{U: 10.5, V: 10.5}, {U: 20.5, V: 30.5}, {U: 10.5, V: 30.5}},
+			},
+		},
+		{
+			name: "fan on pixel centres",
+			min:  nomath.Vec2{U: 4.5, V: 6.5}, max: nomath.Vec2{U: 40.5, V: 50.5},
+			triangles: fanTriangles(nomath.Vec2{U: 4.5, V: 6.5}, nomath.Vec2{U: 40.5, V: 50.5}, nomath.Vec2{U: 22.5, V: 28.5}),
+		},
+		{
+			name: "fan off centre",
+			min:  nomath.Vec2{U: 3.3, V: 4.7}, max: nomath.Vec2{U: 40.2, V: 41.9},
+			triangles: fanTriangles(nomath.Vec2{U: 3.3, V: 4.7}, nomath.Vec2{U: 40.2, V: 41.9}, nomath.Vec2{U: 17.1, V: 22.6}),
+		},
+		{
+			name: "grid on pixel centres",
+			min:  nomath.Vec2{U: 8.5, V: 8.5}, max: nomath.Vec2{U: 56.5, V: 56.5},
+			triangles: gridTriangles(nomath.Vec2{U: 8.5, V: 8.5}, nomath.Vec2{U: 56.5, V: 56.5}, 4, 0),
+		},
+		{
+			name: "jittered grid",
+			min:  nomath.Vec2{U: 2, V: 5}, max: nomath.Vec2{U: 61, V: 58},
+			triangles: gridTriangles(nomath.Vec2{U: 2, V: 5}, nomath.Vec2{U: 61, V: 58}, 6, 1.37),
+		},
+	}
+
+	r := NewRenderer3D()
+	r.Resize(64, 64)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := coverage(r, tt.triangles)
+			for y := 0; y < 64; y++ {
+				for x := 0; x < 64; x++ {
+					// The rectangle's own left and top edges own the pixel
+					// centres on them, its right and bottom edges do not
+					cx, cy := float64(x)+0.5, float64(y)+0.5
+					want := 0
+					if cx >= tt.min.U && cx < tt.max.U && cy >= tt.min.V && cy < tt.max.V {
+						want = 1
+					}
+					if got := counts[y*64+x]; got != want {
+						t.Errorf("pixel (%d, %d) covered %d times, want %d", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEdgeEquationTopLeft(t *testing.T) {
+	// A pixel centre exactly on an edge is inside only for top and left edges
+	tests := []struct {
+		name           string
+		x0, y0, x1, y1 float64
+		inside         bool
+	}{
+		{"top", 0.5, 4.5, 8.5, 4.5, true},
+		{"bottom", 8.5, 4.5, 0.5, 4.5, false},
+		{"left", 4.5, 8.5, 4.5, 0.5, true},
+		{"right", 4.5, 0.5, 4.5, 8.5, false},
+		{"left diagonal", 8.5, 8.5, 0.5, 0.5, true},
+		{"right diagonal", 0.5, 0.5, 8.5, 8.5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEdgeEquation(toSubpixel(tt.x0), toSubpixel(tt.y0), toSubpixel(tt.x1), toSubpixel(tt.y1))
+			if got := e.at(4, 4) >= 0; got != tt.inside {
+				t.Errorf("pixel centre on the edge inside = %v, want %v", got, tt.inside)
+			}
+		})
+	}
+}
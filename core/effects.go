@@ -0,0 +1,315 @@
+package core
+
+import (
+	"GopherEngine/lookdev"
+	"math"
+)
+
+// FogEffect fades surfaces toward Color with distance from the camera.
+// Pixels with nothing drawn are left alone, so match the clear color to it.
+type FogEffect struct {
+	Color lookdev.ColorRGBA
+	Start float64 // Distance where the fog begins
+	End   float64 // Distance where it is fully opaque
+}
+
+func NewFogEffect() *FogEffect {
+	return &FogEffect{Color: lookdev.ColorRGBA{R: 180, G: 185, B: 195, A: 1.0}, Start: 20, End: 200}
+}
+
+func (e *FogEffect) Apply(frame *PostFrame) {
+	linear := e.Color.Linear()
+	fog := [3]float32{linear.R, linear.G, linear.B}
+	span := math.Max(e.End-e.Start, 1e-6)
+	parallelFor(frame.Height, frame.workers, func(y int) {
+		for i := y * frame.Width; i < (y+1)*frame.Width; i++ {
+			if frame.Depth[i] > 1 {
+				continue
+			}
+			amount := float32(math.Max(0, math.Min(1, (frame.Distance(i)-e.Start)/span)))
+			for c := 0; c < 3; c++ {
+				frame.Color[i*3+c] += (fog[c] - frame.Color[i*3+c]) * amount
+			}
+		}
+	})
+}
+
+// VignetteEffect darkens the image toward its corners
+type VignetteEffect struct {
+	Strength float64 // How dark the corners get, 0 to 1
+	Radius   float64 // Where the darkening starts, 0 at the centre to 1 at the corners
+}
+
+func NewVignetteEffect() *VignetteEffect {
+	return &VignetteEffect{Strength: 0.5, Radius: 0.5}
+}
+
+func (e *VignetteEffect) Apply(frame *PostFrame) {
+	parallelFor(frame.Height, frame.workers, func(y int) {
+		dy := (float64(y)+0.5)/float64(frame.Height) - 0.5
+		for x := 0; x < frame.Width; x++ {
+			dx := (float64(x)+0.5)/float64(frame.Width) - 0.5
+			distance := math.Sqrt(dx*dx+dy*dy) / math.Sqrt(0.5)
+			factor := float32(1 - e.Strength*smoothstep(e.Radius, 1, distance))
+			i := (y*frame.Width + x) * 3
+			frame.Color[i] *= factor
+			frame.Color[i+1] *= factor
+			frame.Color[i+2] *= factor
+		}
+	})
+}
+
+// ToneMapEffect scales the image by Exposure and compresses it with the
+// extended Reinhard curve, so values up to WhitePoint fit on screen.
+// Luminance is mapped rather than each channel, which keeps hues intact.
+// The renderer's own ToneMapping still runs afterwards, so leave it at
+// ToneMapExposure to apply this curve alone.
+type ToneMapEffect struct {
+	Exposure   float64
+	WhitePoint float64 // Brightest luminance, mapped to white
+}
+
+func NewToneMapEffect() *ToneMapEffect {
+	return &ToneMapEffect{Exposure: 1.0, WhitePoint: 1.0}
+}
+
+func (e *ToneMapEffect) Apply(frame *PostFrame) {
+	exposure := float32(e.Exposure)
+	white := float32(math.Max(e.WhitePoint, 1e-6))
+	parallelFor(frame.Height, frame.workers, func(y int) {
+		for i := y * frame.Width * 3; i < (y+1)*frame.Width*3; i += 3 {
+			c := frame.Color[i : i+3 : i+3]
+			c[0], c[1], c[2] = c[0]*exposure, c[1]*exposure, c[2]*exposure
+			luma := luminance(c[0], c[1], c[2])
+			if luma <= 0 {
+				continue
+			}
+			scale := (1 + luma/(white*white)) / (1 + luma)
+			c[0], c[1], c[2] = c[0]*scale, c[1]*scale, c[2]*scale
+		}
+	})
+}
+
+// GammaEffect raises every channel to 1/Gamma, so Gamma above 1 brightens
+// the midtones and below 1 darkens them. It adjusts the linear frame, on top
+// of the sRGB encoding every frame gets on output.
+type GammaEffect struct {
+	Gamma float64
+}
+
+func NewGammaEffect() *GammaEffect {
+	return &GammaEffect{Gamma: 2.2}
+}
+
+func (e *GammaEffect) Apply(frame *PostFrame) {
+	exponent := 1 / math.Max(e.Gamma, 1e-6)
+	parallelFor(frame.Height, frame.workers, func(y int) {
+		for i := y * frame.Width * 3; i < (y+1)*frame.Width*3; i++ {
+			if v := frame.Color[i]; v > 0 {
+				frame.Color[i] = float32(math.Pow(float64(v), exponent))
+			}
+		}
+	})
+}
+
+// SharpenEffect is an unsharp mask: each pixel is pushed away from the
+// average of its four neighbours
+type SharpenEffect struct {
+	Amount float64
+
+	source []float32
+}
+
+func NewSharpenEffect() *SharpenEffect {
+	return &SharpenEffect{Amount: 0.5}
+}
+
+func (e *SharpenEffect) Apply(frame *PostFrame) {
+	e.source = append(e.source[:0], frame.Color...)
+	amount := float32(e.Amount)
+	width, height := frame.Width, frame.Height
+	parallelFor(height, frame.workers, func(y int) {
+		up, down := max(0, y-1), min(height-1, y+1)
+		for x := 0; x < width; x++ {
+			left, right := max(0, x-1), min(width-1, x+1)
+			i := (y*width + x) * 3
+			for c := 0; c < 3; c++ {
+				neighbours := e.source[(up*width+x)*3+c] + e.source[(down*width+x)*3+c] +
+					e.source[(y*width+left)*3+c] + e.source[(y*width+right)*3+c]
+				frame.Color[i+c] = e.source[i+c] + amount*(e.source[i+c]-neighbours/4)
+			}
+		}
+	})
+}
+
+// BloomEffect makes bright areas glow by blurring everything brighter than
+// Threshold and adding it back on top
+type BloomEffect struct {
+	Threshold float64 // Luminance above which pixels glow
+	Intensity float64
+	Radius    int // Blur radius in pixels
+
+	bright  []float32
+	scratch []float32
+}
+
+func NewBloomEffect() *BloomEffect {
+	return &BloomEffect{Threshold: 0.8, Intensity: 0.6, Radius: 8}
+}
+
+func (e *BloomEffect) Apply(frame *PostFrame) {
+	if len(e.bright) != len(frame.Color) {
+		e.bright = make([]float32, len(frame.Color))
+		e.scratch = make([]float32, len(frame.Color))
+	}
+
+	// Keep only the part of each pixel above the threshold
+	threshold := float32(e.Threshold)
+	parallelFor(frame.Height, frame.workers, func(y int) {
+		for i := y * frame.Width * 3; i < (y+1)*frame.Width*3; i += 3 {
+			c := frame.Color[i : i+3 : i+3]
+			luma := luminance(c[0], c[1], c[2])
+			scale := float32(0)
+			if luma > threshold {
+				scale = (luma - threshold) / luma
+			}
+			e.bright[i], e.bright[i+1], e.bright[i+2] = c[0]*scale, c[1]*scale, c[2]*scale
+		}
+	})
+
+	gaussianBlur(e.bright, e.scratch, frame.Width, frame.Height, e.Radius, frame.workers)
+
+	intensity := float32(e.Intensity)
+	parallelFor(frame.Height, frame.workers, func(y int) {
+		for i := y * frame.Width * 3; i < (y+1)*frame.Width*3; i++ {
+			frame.Color[i] += e.bright[i] * intensity
+		}
+	})
+}
+
+// DepthOfFieldEffect blurs surfaces by how far they are from the focus
+// distance, like a camera lens
+type DepthOfFieldEffect struct {
+	FocusDistance float64 // Distance that is perfectly sharp
+	FocusRange    float64 // Distance from the focus at which the blur is greatest
+	MaxBlur       float64 // Largest blur radius in pixels
+
+	source []float32
+	blur   []float32 // Blur radius of each pixel
+}
+
+func NewDepthOfFieldEffect() *DepthOfFieldEffect {
+	return &DepthOfFieldEffect{FocusDistance: 20, FocusRange: 40, MaxBlur: 6}
+}
+
+// dofTaps are points spread evenly over the unit disc along a golden angle
+// spiral, scaled by each pixel's blur radius
+var dofTaps = func() [32][2]float64 {
+	var taps [32][2]float64
+	golden := math.Pi * (3 - math.Sqrt(5))
+	for i := range taps {
+		radius := math.Sqrt((float64(i) + 0.5) / float64(len(taps)))
+		angle := float64(i) * golden
+		taps[i] = [2]float64{radius * math.Cos(angle), radius * math.Sin(angle)}
+	}
+	return taps
+}()
+
+func (e *DepthOfFieldEffect) Apply(frame *PostFrame) {
+	width, height := frame.Width, frame.Height
+	e.source = append(e.source[:0], frame.Color...)
+	if len(e.blur) != width*height {
+		e.blur = make([]float32, width*height)
+	}
+	focusRange := math.Max(e.FocusRange, 1e-6)
+	parallelFor(height, frame.workers, func(y int) {
+		for i := y * width; i < (y+1)*width; i++ {
+			defocus := math.Min(1, math.Abs(frame.Distance(i)-e.FocusDistance)/focusRange)
+			e.blur[i] = float32(defocus * e.MaxBlur)
+		}
+	})
+
+	parallelFor(height, frame.workers, func(y int) {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			radius := float64(e.blur[i])
+			if radius < 0.5 {
+				continue
+			}
+
+			// Gather from the disc, taking only neighbours blurry enough to
+			// reach this pixel so sharp surfaces don't bleed into it
+			sum := [3]float32{e.source[i*3], e.source[i*3+1], e.source[i*3+2]}
+			weight := float32(1)
+			for _, tap := range dofTaps {
+				sx := x + int(math.Round(tap[0]*radius))
+				sy := y + int(math.Round(tap[1]*radius))
+				if sx < 0 || sx >= width || sy < 0 || sy >= height {
+					continue
+				}
+				j := sy*width + sx
+				distance := math.Hypot(tap[0], tap[1]) * radius
+				w := float32(math.Max(0, math.Min(1, float64(e.blur[j])-distance+1)))
+				if w == 0 {
+					continue
+				}
+				sum[0] += e.source[j*3] * w
+				sum[1] += e.source[j*3+1] * w
+				sum[2] += e.source[j*3+2] * w
+				weight += w
+			}
+			frame.Color[i*3] = sum[0] / weight
+			frame.Color[i*3+1] = sum[1] / weight
+			frame.Color[i*3+2] = sum[2] / weight
+		}
+	})
+}
+
+func luminance(r, g, b float32) float32 {
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// gaussianBlur blurs an RGB float image in place with up to workers
+// goroutines, using scratch (the same size) for the horizontal pass
+func gaussianBlur(pixels, scratch []float32, width, height, radius, workers int) {
+	if radius < 1 {
+		return
+	}
+	sigma := float64(radius) / 2
+	kernel := make([]float32, radius*2+1)
+	total := float32(0)
+	for k := range kernel {
+		d := float64(k - radius)
+		kernel[k] = float32(math.Exp(-d * d / (2 * sigma * sigma)))
+		total += kernel[k]
+	}
+	for k := range kernel {
+		kernel[k] /= total
+	}
+
+	// Rows into scratch, then columns back into pixels, clamping at the edges
+	parallelFor(height, workers, func(y int) {
+		for x := 0; x < width; x++ {
+			var sum [3]float32
+			for k, w := range kernel {
+				j := (y*width + max(0, min(width-1, x+k-radius))) * 3
+				sum[0] += pixels[j] * w
+				sum[1] += pixels[j+1] * w
+				sum[2] += pixels[j+2] * w
+			}
+			copy(scratch[(y*width+x)*3:], sum[:])
+		}
+	})
+	parallelFor(height, workers, func(y int) {
+		for x := 0; x < width; x++ {
+			var sum [3]float32
+			for k, w := range kernel {
+				j := (max(0, min(height-1, y+k-radius))*width + x) * 3
+				sum[0] += scratch[j] * w
+				sum[1] += scratch[j+1] * w
+				sum[2] += scratch[j+2] * w
+			}
+			copy(pixels[(y*width+x)*3:], sum[:])
+		}
+	})
+}
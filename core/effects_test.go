@@ -0,0 +1,254 @@
+package core
+
+import (
+	"GopherEngine/lookdev"
+	"math"
+	"testing"
+)
+
+// testFrame returns a flat grey frame with every pixel at the given distance
+// from a camera whose planes are at 1 and 100
+func testFrame(width, height int, grey float32, distance float64) *PostFrame {
+	camera := NewPerspectiveCamera()
+	camera.NearPlane, camera.FarPlane = 1, 100
+	frame := &PostFrame{
+		Width:   width,
+		Height:  height,
+		Color:   make([]float32, width*height*3),
+		Depth:   make([]float32, width*height),
+		Camera:  camera,
+		workers: 1,
+	}
+	for i := range frame.Color {
+		frame.Color[i] = grey
+	}
+	for i := range frame.Depth {
+		frame.setDistance(i, distance)
+	}
+	return frame
+}
+
+// setDistance puts the surface at pixel i the given distance from the camera
+func (f *PostFrame) setDistance(i int, distance float64) {
+	near, far := f.Camera.NearPlane, f.Camera.FarPlane
+	ndc := (far + near - 2*far*near/distance) / (far - near)
+	f.Depth[i] = float32((ndc + 1) / 2)
+}
+
+func (f *PostFrame) pixel(x, y int) [3]float32 {
+	i := (y*f.Width + x) * 3
+	return [3]float32{f.Color[i], f.Color[i+1], f.Color[i+2]}
+}
+
+func near32(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+func TestFogEffect(t *testing.T) {
+	frame := testFrame(4, 1, 0, 5)
+	frame.setDistance(1, 20)
+	frame.setDistance(2, 40)
+	frame.Depth[3] = 2 // Nothing drawn
+	if d := frame.Distance(1); math.Abs(d-20) > 1e-3 {
+		t.Fatalf("pixel at distance 20 reads back as %v", d)
+	}
+
+	fog := &FogEffect{Color: lookdev.ColorRGBA{R: 255, G: 51, A: 1}, Start: 10, End: 30}
+	fog.Apply(frame)
+
+	// The fog color blends in linear light
+	g := lookdev.SRGBToLinear(51)
+	for x, want := range [][3]float32{{0, 0, 0}, {0.5, g / 2, 0}, {1, g, 0}, {0, 0, 0}} {
+		got := frame.pixel(x, 0)
+		for c := range got {
+			if math.Abs(float64(got[c]-want[c])) > 1e-3 {
+				t.Errorf("pixel %d is %v, want %v", x, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestVignetteEffect(t *testing.T) {
+	frame := testFrame(9, 9, 0.5, 10)
+	vignette := &VignetteEffect{Strength: 0.5, Radius: 0.5}
+	vignette.Apply(frame)
+
+	centre, edge, corner := frame.pixel(4, 4)[0], frame.pixel(4, 0)[0], frame.pixel(0, 0)[0]
+	if centre != 0.5 {
+		t.Errorf("centre is %v, want it untouched", centre)
+	}
+	if !(corner < edge && edge < centre) || corner < 0.5*0.5 {
+		t.Errorf("centre %v, edge %v and corner %v should darken outward by at most half", centre, edge, corner)
+	}
+	for _, p := range [][2]int{{8, 0}, {0, 8}, {8, 8}} {
+		if got := frame.pixel(p[0], p[1]); got != [3]float32{corner, corner, corner} {
+			t.Errorf("corner %v is %v, want %v like the others", p, got, corner)
+		}
+	}
+}
+
+func TestToneMapEffect(t *testing.T) {
+	frame := testFrame(3, 1, 0, 10)
+	copy(frame.Color, []float32{2, 2, 2, 0.4, 0.2, 0.1, 0, 0, 0})
+	toneMap := &ToneMapEffect{Exposure: 2, WhitePoint: 4}
+	toneMap.Apply(frame)
+
+	// Exposed to the white point, grey 2 maps to white
+	if got := frame.pixel(0, 0); !near32(got[0], 1) || got[0] != got[1] || got[1] != got[2] {
+		t.Errorf("grey at the white point maps to %v, want white", got)
+	}
+
+	// Luminance follows the extended Reinhard curve with the hue kept
+	got := frame.pixel(1, 0)
+	luma := luminance(0.8, 0.4, 0.2)
+	want := luma * (1 + luma/16) / (1 + luma)
+	if !near32(luminance(got[0], got[1], got[2]), want) {
+		t.Errorf("luminance %v maps to %v, want %v", luma, luminance(got[0], got[1], got[2]), want)
+	}
+	if !near32(got[0], 2*got[1]) || !near32(got[1], 2*got[2]) {
+		t.Errorf("color %v lost the 4:2:1 ratio of its channels", got)
+	}
+
+	if got := frame.pixel(2, 0); got != [3]float32{} {
+		t.Errorf("black maps to %v", got)
+	}
+}
+
+func TestGammaEffect(t *testing.T) {
+	frame := testFrame(3, 1, 0, 10)
+	copy(frame.Color, []float32{0.25, 0.25, 0.25, 1, 1, 1})
+	gamma := &GammaEffect{Gamma: 2}
+	gamma.Apply(frame)
+
+	for x, want := range []float32{0.5, 1, 0} {
+		if got := frame.pixel(x, 0); !near32(got[0], want) || got[0] != got[1] || got[1] != got[2] {
+			t.Errorf("pixel %d is %v, want grey %v", x, got, want)
+		}
+	}
+}
+
+func TestSharpenEffect(t *testing.T) {
+	frame := testFrame(5, 5, 0.5, 10)
+	sharpen := &SharpenEffect{Amount: 1}
+	sharpen.Apply(frame)
+	for i, v := range frame.Color {
+		if v != 0.5 {
+			t.Fatalf("channel %d of a flat frame sharpened to %v", i, v)
+		}
+	}
+
+	// A bright dot gets brighter and its neighbours darker
+	frame.Color[(2*5+2)*3] = 1
+	sharpen.Apply(frame)
+	if got := frame.pixel(2, 2)[0]; !near32(got, 1.5) {
+		t.Errorf("dot sharpened to %v, want 1.5", got)
+	}
+	if got := frame.pixel(2, 1)[0]; !near32(got, 0.375) {
+		t.Errorf("neighbour of the dot sharpened to %v, want 0.375", got)
+	}
+	if got := frame.pixel(0, 0)[0]; got != 0.5 {
+		t.Errorf("far corner sharpened to %v, want it untouched", got)
+	}
+}
+
+func TestBloomEffect(t *testing.T) {
+	frame := testFrame(21, 21, 0.5, 10)
+	bloom := &BloomEffect{Threshold: 0.8, Intensity: 0.5, Radius: 3}
+	bloom.Apply(frame)
+	for i, v := range frame.Color {
+		if v != 0.5 {
+			t.Fatalf("channel %d below the threshold bloomed to %v", i, v)
+		}
+	}
+
+	// A dot at luminance 2 spreads the 1.2 above the threshold around it
+	dot := (10*21 + 10) * 3
+	frame.Color[dot], frame.Color[dot+1], frame.Color[dot+2] = 2, 2, 2
+	bloom.Apply(frame)
+
+	if got := frame.pixel(12, 10)[0]; got <= 0.5 {
+		t.Errorf("pixel two away from the dot is %v, want it to glow", got)
+	}
+	if got := frame.pixel(0, 0)[0]; got != 0.5 {
+		t.Errorf("pixel outside the blur radius is %v, want it untouched", got)
+	}
+	added := float32(-1.5)
+	for i := 0; i < len(frame.Color); i += 3 {
+		added += frame.Color[i] - 0.5
+	}
+	if !near32(added, 1.2*0.5) {
+		t.Errorf("bloom added %v in total, want the 1.2 above the threshold at half intensity", added)
+	}
+}
+
+func TestDepthOfFieldEffect(t *testing.T) {
+	// Black on the left, white on the right
+	newFrame := func(leftDistance, rightDistance float64) *PostFrame {
+		frame := testFrame(12, 3, 0, leftDistance)
+		for y := 0; y < 3; y++ {
+			for x := 6; x < 12; x++ {
+				i := y*12 + x
+				frame.Color[i*3], frame.Color[i*3+1], frame.Color[i*3+2] = 1, 1, 1
+				frame.setDistance(i, rightDistance)
+			}
+		}
+		return frame
+	}
+	edge := func(frame *PostFrame) (float32, float32) {
+		return frame.pixel(5, 1)[0], frame.pixel(6, 1)[0]
+	}
+	dof := &DepthOfFieldEffect{FocusDistance: 10, FocusRange: 10, MaxBlur: 3}
+
+	frame := newFrame(10, 10)
+	dof.Apply(frame)
+	if black, white := edge(frame); black != 0 || white != 1 {
+		t.Errorf("edge in focus blurred to %v and %v", black, white)
+	}
+
+	frame = newFrame(50, 50)
+	dof.Apply(frame)
+	if black, white := edge(frame); black <= 0 || white >= 1 {
+		t.Errorf("edge out of focus stayed at %v and %v", black, white)
+	}
+	if got := frame.pixel(0, 1)[0]; got != 0 {
+		t.Errorf("flat area out of focus blurred to %v", got)
+	}
+
+	// A sharp surface stays sharp next to a blurred one
+	frame = newFrame(10, 50)
+	dof.Apply(frame)
+	if black, _ := edge(frame); black != 0 {
+		t.Errorf("surface in focus picked up %v from the blur beside it", black)
+	}
+}
+
+// scaleEffect multiplies every channel by a factor
+type scaleEffect float32
+
+func (e scaleEffect) Apply(frame *PostFrame) {
+	for i := range frame.Color {
+		frame.Color[i] *= float32(e)
+	}
+}
+
+func TestPostEffectsChain(t *testing.T) {
+	r := NewRenderer3D()
+	r.Resize(4, 2)
+	r.Clear(lookdev.ColorRGBA{R: 100, G: 50, B: 200, A: 1})
+
+	// Halving after the gamma curve differs from halving before it
+	r.PostEffects = []PostEffect{&GammaEffect{Gamma: 0.5}, scaleEffect(0.5)}
+	r.postProcess(NewPerspectiveCamera())
+
+	for c, v := range []uint8{100, 50, 200} {
+		linear := lookdev.SRGBToLinear(v)
+		want := lookdev.LinearToSRGB(linear * linear * 0.5)
+		if got := r.Framebuffer[c]; got != want {
+			t.Errorf("channel %d is %d, want %d from the effects in order", c, got, want)
+		}
+	}
+	if r.Framebuffer[3] != 255 {
+		t.Errorf("alpha is %d, want it left opaque", r.Framebuffer[3])
+	}
+}
@@ -0,0 +1,165 @@
+package core
+
+import "math"
+
+// FXAA settings, from the quality preset of FXAA 3.11
+const (
+	fxaaEdgeThresholdMin = 0.0312 // Contrast below this is never an edge, which skips dark areas
+	fxaaEdgeThreshold    = 0.125  // Contrast needed relative to the brightest neighbour
+	fxaaSubpixelQuality  = 0.75   // How strongly single-pixel detail is smoothed
+)
+
+// fxaaSteps are the distances, in pixels, of each step when searching along
+// an edge for its ends. They grow so long edges are found in few steps.
+var fxaaSteps = [...]float64{1, 1, 1, 1, 1, 1.5, 2, 2, 2, 2, 4, 8}
+
+// applyFXAA smooths jagged edges in the output image. Each pixel on an edge is
+// blended toward its neighbour across the edge, by how close it is to the
+// end of the edge's staircase step.
+func (r *Renderer3D) applyFXAA() {
+	if len(r.postScratch) != len(r.Framebuffer) {
+		r.postScratch = make([]uint8, len(r.Framebuffer))
+		r.postLuma = make([]float32, r.outputWidth*r.outputHeight)
+	}
+	copy(r.postScratch, r.Framebuffer)
+	parallelFor(r.outputHeight, r.workers, r.computeLumaRow)
+	parallelFor(r.outputHeight, r.workers, r.fxaaRow)
+}
+
+// outputIndex returns where pixel (x, y) of the output image is in postLuma.
+// Its color starts at four times that in Framebuffer.
+func (r *Renderer3D) outputIndex(x, y int) int {
+	return y*r.outputWidth + x
+}
+
+func (r *Renderer3D) computeLumaRow(y int) {
+	for i := r.outputIndex(0, y); i < r.outputIndex(0, y+1); i++ {
+		p := r.postScratch[i*4 : i*4+3 : i*4+3]
+		r.postLuma[i] = (0.299*float32(p[0]) + 0.587*float32(p[1]) + 0.114*float32(p[2])) / 255
+	}
+}
+
+// luma returns the luma of pixel (x, y), clamped to the screen
+func (r *Renderer3D) luma(x, y int) float64 {
+	x = max(0, min(r.outputWidth-1, x))
+	y = max(0, min(r.outputHeight-1, y))
+	return float64(r.postLuma[r.outputIndex(x, y)])
+}
+
+// lumaBilinear samples the luma between pixel centres
+func (r *Renderer3D) lumaBilinear(x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	tx, ty := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+	top := r.luma(ix, iy)*(1-tx) + r.luma(ix+1, iy)*tx
+	bottom := r.luma(ix, iy+1)*(1-tx) + r.luma(ix+1, iy+1)*tx
+	return top*(1-ty) + bottom*ty
+}
+
+// sampleScratch bilinearly samples the unfiltered frame into the RGB of dst
+func (r *Renderer3D) sampleScratch(x, y float64, dst []uint8) {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	tx, ty := x-x0, y-y0
+	width, height := r.outputWidth, r.outputHeight
+	ix0, iy0 := max(0, min(width-1, int(x0))), max(0, min(height-1, int(y0)))
+	ix1, iy1 := max(0, min(width-1, int(x0)+1)), max(0, min(height-1, int(y0)+1))
+	p00, p10 := r.outputIndex(ix0, iy0)*4, r.outputIndex(ix1, iy0)*4
+	p01, p11 := r.outputIndex(ix0, iy1)*4, r.outputIndex(ix1, iy1)*4
+	for c := 0; c < 3; c++ {
+		top := float64(r.postScratch[p00+c])*(1-tx) + float64(r.postScratch[p10+c])*tx
+		bottom := float64(r.postScratch[p01+c])*(1-tx) + float64(r.postScratch[p11+c])*tx
+		dst[c] = uint8(math.Round(top*(1-ty) + bottom*ty))
+	}
+}
+
+func (r *Renderer3D) fxaaRow(y int) {
+	for x := 0; x < r.outputWidth; x++ {
+		center := r.luma(x, y)
+		up, down := r.luma(x, y-1), r.luma(x, y+1)
+		left, right := r.luma(x-1, y), r.luma(x+1, y)
+
+		lumaMin := min(center, up, down, left, right)
+		lumaMax := max(center, up, down, left, right)
+		lumaRange := lumaMax - lumaMin
+		if lumaRange < math.Max(fxaaEdgeThresholdMin, lumaMax*fxaaEdgeThreshold) {
+			continue
+		}
+
+		upLeft, upRight := r.luma(x-1, y-1), r.luma(x+1, y-1)
+		downLeft, downRight := r.luma(x-1, y+1), r.luma(x+1, y+1)
+		upDown, leftRight := up+down, left+right
+		leftCorners, rightCorners := upLeft+downLeft, upRight+downRight
+		upCorners, downCorners := upLeft+upRight, downLeft+downRight
+
+		// Whether the edge runs horizontally or vertically, from which way
+		// the luma changes the most
+		edgeHorizontal := math.Abs(leftCorners-2*left) + 2*math.Abs(upDown-2*center) + math.Abs(rightCorners-2*right)
+		edgeVertical := math.Abs(upCorners-2*up) + 2*math.Abs(leftRight-2*center) + math.Abs(downCorners-2*down)
+		horizontal := edgeHorizontal >= edgeVertical
+
+		// Pick the side of the pixel the edge is on. step points toward it,
+		// along y for a horizontal edge and x for a vertical one.
+		luma1, luma2 := left, right
+		if horizontal {
+			luma1, luma2 = up, down
+		}
+		gradient1, gradient2 := luma1-center, luma2-center
+		gradientScaled := 0.25 * math.Max(math.Abs(gradient1), math.Abs(gradient2))
+		step, edgeLuma := 1.0, 0.5*(luma2+center)
+		if math.Abs(gradient1) >= math.Abs(gradient2) {
+			step, edgeLuma = -1.0, 0.5*(luma1+center)
+		}
+
+		// Walk both ways along the edge, half a pixel toward it, until the
+		// luma no longer matches the edge, which marks its ends
+		px, py, dx, dy := float64(x)+step*0.5, float64(y), 0.0, 1.0
+		if horizontal {
+			px, py, dx, dy = float64(x), float64(y)+step*0.5, 1.0, 0.0
+		}
+		x1, y1, x2, y2 := px, py, px, py
+		var end1, end2 float64
+		reached1, reached2 := false, false
+		for _, length := range fxaaSteps {
+			if !reached1 {
+				x1, y1 = x1-dx*length, y1-dy*length
+				end1 = r.lumaBilinear(x1, y1) - edgeLuma
+				reached1 = math.Abs(end1) >= gradientScaled
+			}
+			if !reached2 {
+				x2, y2 = x2+dx*length, y2+dy*length
+				end2 = r.lumaBilinear(x2, y2) - edgeLuma
+				reached2 = math.Abs(end2) >= gradientScaled
+			}
+			if reached1 && reached2 {
+				break
+			}
+		}
+
+		// Pixels near the end of an edge that steps the right way are moved
+		// furthest across it, which turns the staircase into a slope
+		distance1 := (px - x1) + (py - y1)
+		distance2 := (x2 - px) + (y2 - py)
+		distance, end := distance1, end1
+		if distance2 < distance1 {
+			distance, end = distance2, end2
+		}
+		offset := 0.0
+		if (end < 0) != (center < edgeLuma) {
+			offset = 0.5 - distance/(distance1+distance2)
+		}
+
+		// Single-pixel detail is smoothed by how much the pixel stands out
+		// from its 3x3 neighbourhood
+		average := (2*(upDown+leftRight) + leftCorners + rightCorners) / 12
+		subpixel := math.Min(1, math.Abs(average-center)/lumaRange)
+		subpixel = (3 - 2*subpixel) * subpixel * subpixel
+		offset = math.Max(offset, subpixel*subpixel*fxaaSubpixelQuality)
+
+		sx, sy := float64(x)+step*offset, float64(y)
+		if horizontal {
+			sx, sy = float64(x), float64(y)+step*offset
+		}
+		i := r.outputIndex(x, y)
+		r.sampleScratch(sx, sy, r.Framebuffer[i*4:i*4+3:i*4+3])
+	}
+}
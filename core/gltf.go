@@ -0,0 +1,86 @@
+package core
+
+import (
+	"GopherEngine/assets"
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"math"
+)
+
+// AddGLTF adds the meshes of an imported glTF scene. Its first camera replaces
+// the scene camera and, when it has lights, they replace the scene's lights.
+func (s *Scene) AddGLTF(g *assets.GLTFScene) {
+	for _, geom := range g.Geometries {
+		s.AddObject(geom)
+	}
+
+	if len(g.Cameras) > 0 {
+		camera := NewCameraFromGLTF(g.Cameras[0])
+		camera.Scene = s
+		s.Camera = camera
+	}
+
+	if len(g.Lights) > 0 {
+		s.Lights = s.Lights[:0]
+		for _, l := range g.Lights {
+			s.Lights = append(s.Lights, NewLightFromGLTF(l))
+		}
+		s.DefaultLight = s.Lights[0]
+		s.Renderer.PreComputeLightDirs(s)
+	}
+}
+
+// NewCameraFromGLTF creates a camera with the placement and projection of an
+// imported one. The aspect ratio always follows the screen.
+func NewCameraFromGLTF(c *assets.GLTFCamera) *PerspectiveCamera {
+	camera := NewPerspectiveCamera()
+	camera.Name = c.Name
+	camera.FocalLength = int(math.Round(c.YFov * 180 / math.Pi))
+	if c.ZNear > 0 {
+		camera.NearPlane = c.ZNear
+	}
+	if c.ZFar > 0 {
+		camera.FarPlane = c.ZFar
+	}
+
+	// The model matrix translates before it rotates, so the position that
+	// puts the eye at c.Position is that point rotated back
+	camera.Transform.SetRotation(nomath.RotationFromMatrix(c.Rotation))
+	camera.Transform.SetPosition(c.Rotation.Transpose().TransformVec3(c.Position))
+	camera.Transform.UpdateModelMatrix()
+	return camera
+}
+
+// NewLightFromGLTF creates a light from a KHR_lights_punctual one. Point and
+// spot lights fall off with the inverse square of the distance, as glTF
+// specifies, and the intensity is used as is.
+func NewLightFromGLTF(l *assets.GLTFLight) *Light {
+	var light *Light
+	switch l.Type {
+	case "point":
+		light = NewPointLight()
+	case "spot":
+		light = NewSpotLight()
+		light.InnerConeAngle = l.InnerConeAngle
+		light.OuterConeAngle = l.OuterConeAngle
+	default:
+		light = NewDirectionalLight()
+	}
+	if l.Name != "" {
+		light.Name = l.Name
+	}
+
+	light.Transform.SetPosition(l.Position)
+	light.Transform.SetRotation(nomath.RotationFromMatrix(l.Rotation))
+	light.Transform.UpdateModelMatrix()
+	if light.Type == LightTypeDirectional {
+		// glTF lights shine down -Z, Direction points back toward the light
+		light.Direction = l.Rotation.TransformVec3(nomath.Vec3{Z: 1}).Normalize()
+	} else {
+		light.Attenuation = Attenuation{Constant: 1.0, Quadratic: 1.0}
+	}
+
+	light.Color = lookdev.NewColorRGBFloat(l.Color.X, l.Color.Y, l.Color.Z)
+	light.Intensity = l.Intensity
+	return light
+}
@@ -4,21 +4,43 @@ import (
 	"GopherEngine/lookdev"
 	"GopherEngine/nomath"
 	"fmt"
+	"math"
 )
 
 const (
 	LightTypeDirectional = 0
 	LightTypePoint       = 1
+	LightTypeSpot        = 2
 )
 
+// Attenuation controls how point lights fade with distance d:
+// 1 / (Constant + Linear*d + Quadratic*d*d)
+type Attenuation struct {
+	Constant  float64
+	Linear    float64
+	Quadratic float64
+}
+
 type Light struct {
 	Name        string
 	Direction   nomath.Vec3
 	Transform   *nomath.Transform
 	Color       *lookdev.ColorRGBA
 	Intensity   float64
-	Attenuation float64
+	Attenuation Attenuation
 	Type        int
+
+	// Spot lights aim along the forward axis of their Transform. Cone angles
+	// are half-angles in radians; light fades out between inner and outer.
+	InnerConeAngle float64
+	OuterConeAngle float64
+
+	// Shadow settings, used by directional and spot lights
+	CastShadows     bool
+	ShadowMapSize   int     // Width and height of the shadow map in texels
+	ShadowBias      float64 // Depth offset that avoids shadow acne
+	ShadowPCFRadius int     // PCF kernel radius in texels (0 = hard shadows)
+	shadowMap       *ShadowMap
 }
 
 func NewPointLight() *Light {
@@ -27,8 +49,12 @@ func NewPointLight() *Light {
 		Transform:   nomath.NewTransform(),
 		Color:       lookdev.NewColorRGBA(),
 		Intensity:   1.0,
-		Attenuation: 1.0,
+		Attenuation: Attenuation{Constant: 1.0, Linear: 0.007, Quadratic: 0.0002},
 		Type:        LightTypePoint,
+
+		ShadowMapSize:   1024,
+		ShadowBias:      0.002,
+		ShadowPCFRadius: 1,
 	}
 	// making light color a white.
 	l.Color.R = 255
@@ -40,14 +66,45 @@ func NewPointLight() *Light {
 	return l
 }
 
+func NewSpotLight() *Light {
+	l := &Light{
+		Name:           "DefaultSpotLight",
+		Transform:      nomath.NewTransform(),
+		Color:          lookdev.NewColorRGBA(),
+		Intensity:      1.0,
+		Attenuation:    Attenuation{Constant: 1.0, Linear: 0.007, Quadratic: 0.0002},
+		Type:           LightTypeSpot,
+		InnerConeAngle: 20.0 * math.Pi / 180.0,
+		OuterConeAngle: 30.0 * math.Pi / 180.0,
+
+		ShadowMapSize:   1024,
+		ShadowBias:      0.0005,
+		ShadowPCFRadius: 1,
+	}
+	// making light color a white.
+	l.Color.R = 255
+	l.Color.G = 255
+	l.Color.B = 255
+	// Hang above the origin pointing straight down
+	l.Transform.SetPosition(nomath.Vec3{X: 0, Y: 30, Z: 0})
+	l.Transform.SetRotation(nomath.Vec3{X: math.Pi / 2})
+	l.Transform.UpdateModelMatrix()
+
+	return l
+}
+
 func NewDirectionalLight() *Light {
 	l := &Light{
 		Name:        "DefaultPointLight",
 		Transform:   nomath.NewTransform(),
 		Color:       lookdev.NewColorRGBA(),
 		Intensity:   10.0,
-		Attenuation: 1.0,
+		Attenuation: Attenuation{Constant: 1.0},
 		Type:        LightTypeDirectional,
+
+		ShadowMapSize:   1024,
+		ShadowBias:      0.002,
+		ShadowPCFRadius: 1,
 	}
 	// making light color a white.
 	l.Color.R = 255
@@ -59,16 +116,86 @@ func NewDirectionalLight() *Light {
 	return l
 }
 
+// GetDirection returns the direction toward the light. For spot lights this
+// is the reverse of the cone axis. Point lights have no single direction, so
+// this is measured from the world origin; use Illuminate for a surface point.
 func (l *Light) GetDirection() nomath.Vec3 {
-	if l.Type == LightTypeDirectional {
+	switch l.Type {
+	case LightTypeDirectional:
 		return l.Direction.Normalize()
+	case LightTypeSpot:
+		return l.Transform.GetForward().Negate()
+	}
+	return l.Transform.GetWorldPosition().Normalize()
+}
+
+// Illuminate returns the direction from point toward the light and how much
+// of the light's energy reaches it.
+func (l *Light) Illuminate(point nomath.Vec3) (nomath.Vec3, float64) {
+	return l.illuminate(point, l.GetDirection())
+}
+
+// illuminate is Illuminate with the light's direction already known, so the
+// renderer can reuse directions computed once per frame.
+func (l *Light) illuminate(point nomath.Vec3, direction nomath.Vec3) (nomath.Vec3, float64) {
+	if l.Type == LightTypeDirectional {
+		return direction, 1.0
+	}
+
+	toLight := l.Transform.GetWorldPosition().Subtract(point)
+	distance := toLight.Length()
+	if distance == 0 {
+		return nomath.Vec3{Y: 1}, 1.0
+	}
+	toLight = toLight.Multiply(1.0 / distance)
+	attenuation := l.Attenuation.At(distance)
+
+	if l.Type == LightTypeSpot {
+		attenuation *= l.coneFalloff(toLight.Dot(direction))
+	}
+	return toLight, attenuation
+}
+
+// coneFalloff smoothly fades a spot light from its inner to its outer cone.
+// cosAngle is the cosine of the angle between the cone axis and the point.
+func (l *Light) coneFalloff(cosAngle float64) float64 {
+	cosOuter := math.Cos(l.OuterConeAngle)
+	cosInner := math.Cos(math.Min(l.InnerConeAngle, l.OuterConeAngle))
+	if cosAngle <= cosOuter {
+		return 0
+	}
+	if cosAngle >= cosInner || cosInner == cosOuter {
+		return 1
+	}
+	t := (cosAngle - cosOuter) / (cosInner - cosOuter)
+	return t * t * (3 - 2*t)
+}
+
+// At returns the attenuation factor at the given distance
+func (a Attenuation) At(distance float64) float64 {
+	denom := a.Constant + a.Linear*distance + a.Quadratic*distance*distance
+	if denom <= 0 {
+		return 1.0
+	}
+	return 1.0 / denom
+}
+
+// Radiance returns the light color in linear light scaled by its intensity,
+// per channel. It is not clamped, so bright lights go past white.
+func (l *Light) Radiance() nomath.Vec3 {
+	if l.Color == nil {
+		return nomath.Vec3{X: l.Intensity, Y: l.Intensity, Z: l.Intensity}
+	}
+	color := l.Color.Linear()
+	return nomath.Vec3{
+		X: float64(color.R) * l.Intensity,
+		Y: float64(color.G) * l.Intensity,
+		Z: float64(color.B) * l.Intensity,
 	}
-	// Optional: default to zero direction
-	return nomath.Vec3{X: 0, Y: 0, Z: 0}
 }
 
 func (l *Light) String() string {
-	return fmt.Sprintf("Light(%s, %s)", l.Name, l.Type)
+	return fmt.Sprintf("Light(%s, %d)", l.Name, l.Type)
 }
 
 func (l *Light) Update() {
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"math"
+	"testing"
+)
+
+func TestAttenuation(t *testing.T) {
+	a := Attenuation{Constant: 1, Linear: 0.5, Quadratic: 0.25}
+	tests := []struct {
+		distance, want float64
+	}{
+		{0, 1},
+		{2, 1.0 / 3},
+		{4, 1.0 / 7},
+	}
+	for _, tt := range tests {
+		if got := a.At(tt.distance); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("At(%v) = %v, want %v", tt.distance, got, tt.want)
+		}
+	}
+	if got := (Attenuation{}).At(10); got != 1 {
+		t.Errorf("zero attenuation gives %v, want no falloff", got)
+	}
+}
+
+func TestPointLightIlluminate(t *testing.T) {
+	light := NewPointLight()
+	light.Attenuation = Attenuation{Constant: 1, Quadratic: 1}
+	light.Transform.SetPosition(nomath.Vec3{X: 1, Y: 2, Z: 3})
+	light.Update()
+
+	tests := []struct {
+		point           nomath.Vec3
+		wantDir         nomath.Vec3
+		wantAttenuation float64
+	}{
+		{nomath.Vec3{X: 1, Y: 0, Z: 3}, nomath.Vec3{Y: 1}, 1.0 / 5},
+		{nomath.Vec3{X: 4, Y: 2, Z: 3}, nomath.Vec3{X: -1}, 1.0 / 10},
+		{nomath.Vec3{X: 1, Y: 2, Z: 3}, nomath.Vec3{Y: 1}, 1},
+	}
+	for _, tt := range tests {
+		dir, attenuation := light.Illuminate(tt.point)
+		if dir.Subtract(tt.wantDir).Length() > 1e-12 || math.Abs(attenuation-tt.wantAttenuation) > 1e-12 {
+			t.Errorf("Illuminate(%v) = %v, %v, want %v, %v", tt.point, dir, attenuation, tt.wantDir, tt.wantAttenuation)
+		}
+	}
+
+	// Directional lights reach everywhere at full strength
+	sun := NewDirectionalLight()
+	sun.Direction = nomath.Vec3{Y: 2}
+	if dir, attenuation := sun.Illuminate(nomath.Vec3{X: 100}); dir != (nomath.Vec3{Y: 1}) || attenuation != 1 {
+		t.Errorf("directional light gives %v, %v, want (0, 1, 0), 1", dir, attenuation)
+	}
+}
+
+func TestLightRadiance(t *testing.T) {
+	light := NewPointLight()
+	light.Color = &lookdev.ColorRGBA{R: 255, G: 0, B: 255, A: 1}
+	light.Intensity = 3
+	if got := light.Radiance(); got != (nomath.Vec3{X: 3, Y: 0, Z: 3}) {
+		t.Errorf("got %v, want the color scaled by the intensity", got)
+	}
+}
+
+func TestSpotLightCone(t *testing.T) {
+	light := NewSpotLight()
+	light.Attenuation = Attenuation{Constant: 1}
+	light.Transform.SetPosition(nomath.Vec3{Y: 10})
+	light.Update()
+	if dir := light.GetDirection(); dir.Subtract(nomath.Vec3{Y: 1}).Length() > 1e-9 {
+		t.Fatalf("default spot light points along %v, want straight down", dir.Negate())
+	}
+
+	// Halfway between the 20 and 30 degree cones in cosine
+	cosInner, cosOuter := math.Cos(20*math.Pi/180), math.Cos(30*math.Pi/180)
+	halfway := math.Acos((cosInner + cosOuter) / 2)
+	tests := []struct {
+		name  string
+		angle float64
+		want  float64
+	}{
+		{"on the axis", 0, 1},
+		{"inside the inner cone", 15 * math.Pi / 180, 1},
+		{"between the cones", halfway, 0.5},
+		{"outside the outer cone", 35 * math.Pi / 180, 0},
+		{"behind", math.Pi * 0.75, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A point at the given angle from the axis, 10 units from the light
+			point := nomath.Vec3{X: 10 * math.Sin(tt.angle), Y: 10 - 10*math.Cos(tt.angle)}
+			_, attenuation := light.Illuminate(point)
+			if math.Abs(attenuation-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", attenuation, tt.want)
+			}
+		})
+	}
+
+	// The falloff is smooth and only ever fades outward
+	previous := 1.0
+	for degrees := 20.0; degrees <= 30; degrees += 0.5 {
+		falloff := light.coneFalloff(math.Cos(degrees * math.Pi / 180))
+		if falloff > previous {
+			t.Errorf("falloff rises to %v at %v degrees", falloff, degrees)
+		}
+		previous = falloff
+	}
+}
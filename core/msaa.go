@@ -0,0 +1,216 @@
+package core
+
+import (
+	"image"
+	"math"
+)
+
+// maxSamples is the highest supported MSAA sample count
+const maxSamples = 8
+
+// samplePosition is a sample's offset from the pixel centre in subpixels
+type samplePosition struct {
+	x, y int64
+}
+
+// samplePatterns are the standard rotated-grid MSAA sample positions, given
+// in sixteenths of a pixel and scaled to subpixels, keyed by sample count
+var samplePatterns = map[int][]samplePosition{
+	2: scaleSamplePattern([]samplePosition{{4, 4}, {-4, -4}}),
+	4: scaleSamplePattern([]samplePosition{{-2, -6}, {6, -2}, {-6, 2}, {2, 6}}),
+	8: scaleSamplePattern([]samplePosition{{1, -3}, {-1, 3}, {5, 1}, {-3, -5}, {-5, 5}, {-7, -1}, {3, 7}, {7, -7}}),
+}
+
+func scaleSamplePattern(pattern []samplePosition) []samplePosition {
+	for i := range pattern {
+		pattern[i].x *= subpixelOne / 16
+		pattern[i].y *= subpixelOne / 16
+	}
+	return pattern
+}
+
+// msaaSampleCount rounds a requested MSAASamples down to a supported count
+func msaaSampleCount(requested int) int {
+	switch {
+	case requested >= 8:
+		return 8
+	case requested >= 4:
+		return 4
+	case requested >= 2:
+		return 2
+	}
+	return 1
+}
+
+// prepareMSAA switches the sample buffers over when MSAASamples changed.
+// Whatever was drawn so far is carried over, so it can change between any
+// two draws.
+func (r *Renderer3D) prepareMSAA() {
+	samples := msaaSampleCount(r.MSAASamples)
+	if samples == r.samples {
+		return
+	}
+	r.Resolve()
+	r.samples = samples
+	r.allocateSamples()
+}
+
+// allocateSamples sizes the sample buffers for the current resolution and
+// sample count, starting every sample out as its pixel
+func (r *Renderer3D) allocateSamples() {
+	if r.samples <= 1 {
+		r.sampleColor, r.sampleDepth = nil, nil
+		return
+	}
+	pixels := r.width * r.height
+	r.sampleColor = make([]float32, pixels*r.samples*3)
+	r.sampleDepth = make([]float32, pixels*r.samples)
+	r.expandSamples()
+}
+
+// expandSamples copies every pixel of the color and depth buffers into all
+// of its samples
+func (r *Renderer3D) expandSamples() {
+	n := r.samples
+	for i := range r.DepthBuffer {
+		pixel := r.ColorBuffer[i*3 : i*3+3]
+		for s := i * n; s < (i+1)*n; s++ {
+			copy(r.sampleColor[s*3:s*3+3], pixel)
+			r.sampleDepth[s] = r.DepthBuffer[i]
+		}
+	}
+}
+
+// Resolve averages the samples of every pixel into the color buffer and keeps
+// the nearest sample depth in the depth buffer. Without MSAA it does nothing.
+func (r *Renderer3D) Resolve() {
+	if r.samples <= 1 {
+		return
+	}
+	parallelFor(r.GetHeight(), r.workers, func(y int) {
+		r.resolveDepthRow(y)
+		r.resolveColorRow(y)
+	})
+}
+
+// resolveDepth fills the depth buffer from the samples, for the passes that
+// work per pixel, such as SSAO
+func (r *Renderer3D) resolveDepth() {
+	if r.samples <= 1 {
+		return
+	}
+	parallelFor(r.GetHeight(), r.workers, r.resolveDepthRow)
+}
+
+func (r *Renderer3D) resolveDepthRow(y int) {
+	n := r.samples
+	for i := r.pixelIndex(0, y); i < r.pixelIndex(0, y+1); i++ {
+		nearest := float32(math.MaxFloat32)
+		for _, depth := range r.sampleDepth[i*n : (i+1)*n] {
+			nearest = min(nearest, depth)
+		}
+		r.DepthBuffer[i] = nearest
+	}
+}
+
+func (r *Renderer3D) resolveColorRow(y int) {
+	n := r.samples
+	for i := r.pixelIndex(0, y); i < r.pixelIndex(0, y+1); i++ {
+		var sum [3]float32
+		samples := r.sampleColor[i*n*3 : (i+1)*n*3]
+		for s := 0; s < len(samples); s += 3 {
+			sum[0] += samples[s]
+			sum[1] += samples[s+1]
+			sum[2] += samples[s+2]
+		}
+		pixel := r.ColorBuffer[i*3 : i*3+3 : i*3+3]
+		for c := range pixel {
+			pixel[c] = sum[c] / float32(n)
+		}
+	}
+}
+
+// rasterizeSamples is rasterizeTriangle with MSAA. Coverage and depth are
+// tested per sample, but each pixel is shaded only once and the color is
+// stored in every sample that passed.
+func (r *Renderer3D) rasterizeSamples(st *screenTriangle, area image.Rectangle, shader *fragmentShader) {
+	n := r.samples
+	pattern := samplePatterns[n]
+	depth0, depth1, depth2 := st.depth[0], st.depth[1], st.depth[2]
+	e0, e1, e2 := st.edges[0], st.edges[1], st.edges[2]
+
+	// How much each edge function changes from the pixel centre to each sample
+	var offsets [3][maxSamples]int64
+	for k, e := range st.edges {
+		for s, p := range pattern {
+			offsets[k][s] = e.dx*p.y - e.dy*p.x
+		}
+	}
+
+	row0, row1, row2 := e0.at(area.Min.X, area.Min.Y), e1.at(area.Min.X, area.Min.Y), e2.at(area.Min.X, area.Min.Y)
+	for y := area.Min.Y; y <= area.Max.Y; y, row0, row1, row2 = y+1, row0+e0.stepY, row1+e1.stepY, row2+e2.stepY {
+		w0, w1, w2 := row0, row1, row2
+		for x := area.Min.X; x <= area.Max.X; x, w0, w1, w2 = x+1, w0+e0.stepX, w1+e1.stepX, w2+e2.stepX {
+			i := r.pixelIndex(x, y)
+			base := i * n
+
+			var mask uint8
+			var depths [maxSamples]float32
+			var sumU, sumV, sumW float64
+			for s := 0; s < n; s++ {
+				s0, s1, s2 := w0+offsets[0][s], w1+offsets[1][s], w2+offsets[2][s]
+				if s0|s1|s2 < 0 {
+					continue
+				}
+				u := float64(s0-e0.bias) * st.invArea
+				v := float64(s1-e1.bias) * st.invArea
+				w := float64(s2-e2.bias) * st.invArea
+				depth := u*depth0 + v*depth1 + w*depth2
+				if depth < 0 || depth > 1 || depth >= float64(r.sampleDepth[base+s]) {
+					continue
+				}
+				mask |= 1 << s
+				depths[s] = float32(depth)
+				sumU, sumV, sumW = sumU+u, sumV+v, sumW+w
+			}
+			if mask == 0 {
+				continue
+			}
+
+			// Shade at the pixel centre when it is inside the triangle,
+			// otherwise at the middle of the samples that passed, so the
+			// attributes are never extrapolated past the edges
+			u := float64(w0-e0.bias) * st.invArea
+			v := float64(w1-e1.bias) * st.invArea
+			w := float64(w2-e2.bias) * st.invArea
+			if w0|w1|w2 < 0 {
+				total := sumU + sumV + sumW
+				u, v, w = sumU/total, sumV/total, sumW/total
+			}
+			color, diffuse, weights, ok := shader.shade(u, v, w)
+			if !ok {
+				continue
+			}
+
+			if st.setup.blend {
+				alpha := shader.opacity(diffuse, weights)
+				for s := 0; s < n; s++ {
+					if mask&(1<<s) != 0 {
+						r.blendSample(base+s, color, alpha)
+					}
+				}
+				continue
+			}
+			for s := 0; s < n; s++ {
+				if mask&(1<<s) != 0 {
+					sample := r.sampleColor[(base+s)*3 : (base+s)*3+3 : (base+s)*3+3]
+					sample[0], sample[1], sample[2] = color.R, color.G, color.B
+					r.sampleDepth[base+s] = depths[s]
+				}
+			}
+			if shader.writeSSAO {
+				r.writeSSAOSample(i, shader.normal(weights), diffuse)
+			}
+		}
+	}
+}
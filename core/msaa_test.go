@@ -0,0 +1,81 @@
+package core
+
+import (
+	"GopherEngine/lookdev"
+	"fmt"
+	"testing"
+)
+
+func TestMSAAResolve(t *testing.T) {
+	for _, samples := range []int{2, 4, 8} {
+		t.Run(fmt.Sprintf("%dx", samples), func(t *testing.T) {
+			r := NewRenderer3D()
+			r.MSAASamples = samples
+			r.Resize(8, 4)
+			r.Clear(lookdev.ColorRGBA{R: 40, G: 80, B: 120, A: 1})
+			if r.samples != samples {
+				t.Fatalf("%d samples in use, want %d", r.samples, samples)
+			}
+
+			// Give pixel 9 a different color and depth in every sample
+			const pixel = 9
+			var want [3]float32
+			for s := 0; s < samples; s++ {
+				c := r.sampleColor[(pixel*samples+s)*3:]
+				c[0], c[1], c[2] = float32(s), float32(s)*0.5, 2
+				r.sampleDepth[pixel*samples+s] = 0.9 - float32(s)*0.1
+				want[0] += float32(s) / float32(samples)
+				want[1] += float32(s) * 0.5 / float32(samples)
+				want[2] += 2 / float32(samples)
+			}
+			r.Resolve()
+
+			for c, v := range r.ColorBuffer[pixel*3 : pixel*3+3] {
+				if v < want[c]-1e-6 || v > want[c]+1e-6 {
+					t.Errorf("channel %d resolved to %v, want the sample average %v", c, v, want[c])
+				}
+			}
+			if depth, nearest := r.DepthBuffer[pixel], 0.9-float32(samples-1)*0.1; depth != nearest {
+				t.Errorf("depth resolved to %v, want the nearest sample %v", depth, nearest)
+			}
+		})
+	}
+}
+
+func TestClearOpaque(t *testing.T) {
+	color := lookdev.ColorRGBA{R: 40, G: 80, B: 120, A: 1}
+	for _, samples := range []int{1, 2, 4, 8} {
+		t.Run(fmt.Sprintf("%dx", samples), func(t *testing.T) {
+			r := NewRenderer3D()
+			r.Resize(8, 4)
+
+			// Switch MSAA on after a frame cleared to another color, and zero
+			// the output so encodeOutput has to write the alpha
+			r.Clear(lookdev.ColorRGBA{R: 255, A: 1})
+			for i := range r.Framebuffer {
+				r.Framebuffer[i] = 0
+			}
+			r.MSAASamples = samples
+			r.Clear(color)
+			r.Resolve()
+			r.encodeOutput()
+
+			for i := 0; i < len(r.Framebuffer); i += 4 {
+				p := r.Framebuffer[i : i+4]
+				if p[3] != 255 {
+					t.Fatalf("pixel %d has alpha %d after Clear, want 255", i/4, p[3])
+				}
+				for c, want := range []uint8{color.R, color.G, color.B} {
+					if diff := int(p[c]) - int(want); diff < -1 || diff > 1 {
+						t.Fatalf("pixel %d is %v after Clear, want %v", i/4, p[:3], []uint8{color.R, color.G, color.B})
+					}
+				}
+			}
+			for i, depth := range r.DepthBuffer {
+				if depth < 1 {
+					t.Fatalf("pixel %d has depth %v after Clear, want it empty", i, depth)
+				}
+			}
+		})
+	}
+}
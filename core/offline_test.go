@@ -0,0 +1,26 @@
+package core
+
+import "testing"
+
+func TestFrameFilename(t *testing.T) {
+	tests := []struct {
+		pattern       string
+		frame, frames int
+		want          string
+	}{
+		{"frame_%04d.png", 7, 10, "frame_0007.png"},
+		{"frame_%d.png", 12, 20, "frame_12.png"},
+		{"out/%03d/frame_%04d.png", 3, 10, "out/%03d/frame_0003.png"},
+		{"out%20dir/frame.png", 3, 10, "out%20dir/frame_0003.png"},
+		{"out%20dir/frame.png", 0, 1, "out%20dir/frame.png"},
+		{"100%.png", 2, 5, "100%_0002.png"},
+		{"render.png", 0, 1, "render.png"},
+		{"render.png", 5, 10, "render_0005.png"},
+		{"frames.d/render", 5, 10, "frames.d/render_0005"},
+	}
+	for _, tt := range tests {
+		if got := FrameFilename(tt.pattern, tt.frame, tt.frames); got != tt.want {
+			t.Errorf("FrameFilename(%q, %d, %d) = %q, want %q", tt.pattern, tt.frame, tt.frames, got, tt.want)
+		}
+	}
+}
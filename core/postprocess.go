@@ -0,0 +1,164 @@
+package core
+
+import (
+	"GopherEngine/lookdev"
+	"math"
+)
+
+// PostEffect is a screen-space effect run over each finished frame, such as
+// fog or bloom. Effects are chained in Renderer3D.PostEffects and their
+// settings can be changed between frames.
+type PostEffect interface {
+	Apply(frame *PostFrame)
+}
+
+// PostFrame is the frame handed to post effects, in linear HDR before tone
+// mapping, so effects work in real light values.
+type PostFrame struct {
+	Width, Height int
+	Color         []float32 // Linear RGB, three per pixel row by row; 1 is white before tone mapping, but values go above it
+	Depth         []float32 // 0 at the near plane to 1 at the far plane, above 1 where nothing was drawn
+	Camera        *PerspectiveCamera
+
+	workers int // Goroutines the built-in effects spread rows over
+}
+
+// Distance returns how far from the camera the surface at pixel i is, or the
+// far plane distance where nothing was drawn
+func (f *PostFrame) Distance(i int) float64 {
+	near, far := f.Camera.NearPlane, f.Camera.FarPlane
+	depth := float64(f.Depth[i])
+	if depth > 1 {
+		return far
+	}
+	ndc := depth*2 - 1
+	return 2 * far * near / (far + near - ndc*(far-near))
+}
+
+// postProcess turns the finished HDR frame into the output image: the
+// PostEffects chain at the render resolution, filtering a supersampled frame
+// down to the output size, tone mapping and sRGB encoding, then FXAA
+func (r *Renderer3D) postProcess(camera *PerspectiveCamera) {
+	if len(r.PostEffects) > 0 {
+		frame := &PostFrame{
+			Width:  r.GetWidth(),
+			Height: r.GetHeight(),
+			Color:  r.ColorBuffer,
+			Depth:  r.DepthBuffer,
+			Camera: camera,
+
+			workers: r.workers,
+		}
+		for _, effect := range r.PostEffects {
+			effect.Apply(frame)
+		}
+	}
+	r.downsample()
+	r.encodeOutput()
+	if r.FXAAEnabled {
+		r.applyFXAA()
+	}
+}
+
+// encodeOutput tone maps the HDR frame and stores it sRGB encoded in
+// Framebuffer
+func (r *Renderer3D) encodeOutput() {
+	exposure, white := float32(r.Exposure), float32(r.WhitePoint)
+	parallelFor(r.outputHeight, r.workers, func(y int) {
+		for i := y * r.outputWidth; i < (y+1)*r.outputWidth; i++ {
+			var c [3]float32
+			copy(c[:], r.outputColor[i*3:i*3+3])
+			toneMap(c[:], r.ToneMapping, exposure, white)
+			p := r.Framebuffer[i*4 : i*4+4 : i*4+4]
+			p[0], p[1], p[2], p[3] = lookdev.LinearToSRGB(c[0]), lookdev.LinearToSRGB(c[1]), lookdev.LinearToSRGB(c[2]), 255
+		}
+	})
+}
+
+// toneMap scales a linear color by exposure and compresses it into 0 to 1
+// with the given operator. ToneMapExposure leaves clipping to the encoding.
+func toneMap(c []float32, operator int, exposure, white float32) {
+	c[0], c[1], c[2] = c[0]*exposure, c[1]*exposure, c[2]*exposure
+	switch operator {
+	case ToneMapReinhard:
+		// Mapped on luminance rather than per channel, which keeps hues
+		luma := luminance(c[0], c[1], c[2])
+		if luma <= 0 {
+			return
+		}
+		white = max(white, 1e-6)
+		scale := (1 + luma/(white*white)) / (1 + luma)
+		c[0], c[1], c[2] = c[0]*scale, c[1]*scale, c[2]*scale
+	case ToneMapACES:
+		for k, v := range c {
+			c[k] = acesFilmic(v)
+		}
+	}
+}
+
+// acesFilmic is Krzysztof Narkowicz's fit of the ACES filmic curve
+func acesFilmic(v float32) float32 {
+	v = max(0, v)
+	return min(1, v*(2.51*v+0.03)/(v*(2.43*v+0.59)+0.14))
+}
+
+// filterTap is one source pixel's share of a filtered pixel
+type filterTap struct {
+	index  int
+	weight float32
+}
+
+// boxFilterTaps returns, for each of dst pixels along an axis, the src
+// pixels it covers weighted by how much of each it covers. With a whole
+// multiple this averages an ordered grid of samples per output pixel.
+func boxFilterTaps(src, dst int) [][]filterTap {
+	scale := float64(src) / float64(dst)
+	taps := make([][]filterTap, dst)
+	for d := range taps {
+		start, end := float64(d)*scale, float64(d+1)*scale
+		for s := int(start); s < src && float64(s) < end; s++ {
+			overlap := math.Min(end, float64(s+1)) - math.Max(start, float64(s))
+			if overlap > 0 {
+				taps[d] = append(taps[d], filterTap{index: s, weight: float32(overlap / scale)})
+			}
+		}
+	}
+	return taps
+}
+
+// downsample filters the supersampled frame down to the output size, first
+// along the rows and then down the columns. Averaging in linear light keeps
+// bright edges from thinning out.
+func (r *Renderer3D) downsample() {
+	if len(r.downsampleX) == 0 {
+		return
+	}
+	stride := r.outputWidth * 3
+
+	parallelFor(r.GetHeight(), r.workers, func(y int) {
+		src := r.ColorBuffer[r.pixelIndex(0, y)*3 : r.pixelIndex(0, y+1)*3]
+		row := r.downsampleRows[y*stride : (y+1)*stride]
+		for x, taps := range r.downsampleX {
+			var sum [3]float32
+			for _, tap := range taps {
+				p := src[tap.index*3 : tap.index*3+3 : tap.index*3+3]
+				sum[0] += p[0] * tap.weight
+				sum[1] += p[1] * tap.weight
+				sum[2] += p[2] * tap.weight
+			}
+			copy(row[x*3:x*3+3], sum[:])
+		}
+	})
+
+	parallelFor(r.outputHeight, r.workers, func(y int) {
+		out := r.outputColor[y*stride : (y+1)*stride]
+		taps := r.downsampleY[y]
+		for i := range out {
+			sum := float32(0)
+			for _, tap := range taps {
+				sum += r.downsampleRows[tap.index*stride+i] * tap.weight
+			}
+			out[i] = sum
+		}
+	})
+}
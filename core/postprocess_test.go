@@ -0,0 +1,161 @@
+package core
+
+import (
+	"GopherEngine/lookdev"
+	"fmt"
+	"image"
+	"testing"
+)
+
+func TestOutputSize(t *testing.T) {
+	tests := []struct {
+		width, height int
+		supersampling float64
+		fxaa          bool
+		msaa          int
+	}{
+		{320, 240, 1, false, 1},
+		{320, 240, 1, true, 1},
+		{320, 240, 2, false, 1},
+		{321, 241, 1.5, true, 1},
+		{100, 75, 1.25, true, 4},
+		{1, 1, 2, true, 2},
+	}
+
+	background := lookdev.ColorRGBA{R: 30, G: 90, B: 200, A: 1}
+	for _, tt := range tests {
+		name := fmt.Sprintf("%dx%d at %gx, FXAA %v, MSAA %d", tt.width, tt.height, tt.supersampling, tt.fxaa, tt.msaa)
+		t.Run(name, func(t *testing.T) {
+			s := NewScene()
+			s.Renderer.Supersampling = tt.supersampling
+			s.Renderer.FXAAEnabled = tt.fxaa
+			s.Renderer.MSAASamples = tt.msaa
+			s.Background = background
+			s.RenderFrame(tt.width, tt.height)
+
+			renderWidth, renderHeight := tt.width, tt.height
+			if tt.supersampling > 1 {
+				renderWidth = int(float64(tt.width)*tt.supersampling + 0.5)
+				renderHeight = int(float64(tt.height)*tt.supersampling + 0.5)
+			}
+			if w, h := s.Renderer.GetWidth(), s.Renderer.GetHeight(); w != renderWidth || h != renderHeight {
+				t.Errorf("rendered at %dx%d, want %dx%d", w, h, renderWidth, renderHeight)
+			}
+			img := s.Renderer.ToImage()
+			if want := image.Rect(0, 0, tt.width, tt.height); img.Bounds() != want || len(img.Pix) != tt.width*tt.height*4 {
+				t.Errorf("output image is %v with %d bytes, want %v", img.Bounds(), len(img.Pix), want)
+			}
+
+			// Filtering and FXAA must leave an empty, flat frame as it was
+			s.Renderer.Clear(background)
+			s.Renderer.postProcess(s.Camera)
+			for i := 0; i < len(img.Pix); i += 4 {
+				p := img.Pix[i : i+4]
+				for c, want := range []uint8{background.R, background.G, background.B, 255} {
+					if diff := int(p[c]) - int(want); diff < -1 || diff > 1 {
+						t.Fatalf("pixel %d is %v, want %v", i/4, p, []uint8{background.R, background.G, background.B, 255})
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBoxFilterTaps(t *testing.T) {
+	tests := []struct {
+		src, dst int
+	}{
+		{640, 320}, {480, 240}, {482, 321}, {125, 100}, {2, 1}, {3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d to %d", tt.src, tt.dst), func(t *testing.T) {
+			taps := boxFilterTaps(tt.src, tt.dst)
+			if len(taps) != tt.dst {
+				t.Fatalf("got %d output pixels, want %d", len(taps), tt.dst)
+			}
+
+			// Every output pixel's weights sum to one and every source pixel
+			// is used in full
+			used := make([]float32, tt.src)
+			for d, pixel := range taps {
+				sum := float32(0)
+				for _, tap := range pixel {
+					sum += tap.weight
+					used[tap.index] += tap.weight * float32(tt.src) / float32(tt.dst)
+				}
+				if sum < 1-1e-5 || sum > 1+1e-5 {
+					t.Errorf("output pixel %d weights sum to %v", d, sum)
+				}
+			}
+			for s, u := range used {
+				if u < 1-1e-4 || u > 1+1e-4 {
+					t.Errorf("source pixel %d used %v times", s, u)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeOutputToneMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator int
+		exposure float64
+		white    float64
+		in, want [3]float32 // Linear, before and after tone mapping
+	}{
+		{"exposure passes through", ToneMapExposure, 1, 11, [3]float32{0.5, 0.25, 0}, [3]float32{0.5, 0.25, 0}},
+		{"exposure scales", ToneMapExposure, 2, 11, [3]float32{0.25, 0.1, 0.05}, [3]float32{0.5, 0.2, 0.1}},
+		{"exposure clips", ToneMapExposure, 1, 11, [3]float32{2, 1.5, 0.5}, [3]float32{2, 1.5, 0.5}},
+		{"Reinhard grey", ToneMapReinhard, 1, 11, [3]float32{1, 1, 1}, [3]float32{0.5041322, 0.5041322, 0.5041322}},
+		{"Reinhard dark", ToneMapReinhard, 1, 11, [3]float32{0.25, 0.25, 0.25}, [3]float32{0.2004132, 0.2004132, 0.2004132}},
+		{"Reinhard keeps hue", ToneMapReinhard, 1, 11, [3]float32{4, 2, 1}, [3]float32{1.2161602, 0.6080801, 0.3040400}},
+		{"Reinhard white point", ToneMapReinhard, 1, 11, [3]float32{11, 11, 11}, [3]float32{1, 1, 1}},
+		{"Reinhard exposure", ToneMapReinhard, 0.5, 2, [3]float32{4, 4, 4}, [3]float32{1, 1, 1}},
+		{"Reinhard black", ToneMapReinhard, 1, 11, [3]float32{0, 0, 0}, [3]float32{0, 0, 0}},
+		{"ACES", ToneMapACES, 1, 11, [3]float32{1, 0.18, 0.5}, [3]float32{0.8037975, 0.2668989, 0.6163070}},
+		{"ACES clips", ToneMapACES, 1, 11, [3]float32{10, 0, -1}, [3]float32{1, 0, 0}},
+	}
+
+	r := NewRenderer3D()
+	r.Resize(1, 1)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.in
+			toneMap(got[:], tt.operator, float32(tt.exposure), float32(tt.white))
+			for c := range got {
+				if got[c] < tt.want[c]-1e-5 || got[c] > tt.want[c]+1e-5 {
+					t.Errorf("tone mapped to %v, want %v", got, tt.want)
+					break
+				}
+			}
+
+			r.ToneMapping, r.Exposure, r.WhitePoint = tt.operator, tt.exposure, tt.white
+			copy(r.ColorBuffer, tt.in[:])
+			r.encodeOutput()
+			want := []uint8{lookdev.LinearToSRGB(tt.want[0]), lookdev.LinearToSRGB(tt.want[1]), lookdev.LinearToSRGB(tt.want[2]), 255}
+			for c, v := range r.Framebuffer {
+				if diff := int(v) - int(want[c]); diff < -1 || diff > 1 {
+					t.Errorf("encoded as %v, want %v", r.Framebuffer, want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultToneMappingKeepsColors(t *testing.T) {
+	// Unlit, a surface's color only meets the ambient light, so by default
+	// it reaches the screen unchanged
+	s := newTestScene()
+	color := lookdev.ColorRGBA{R: 100, G: 150, B: 200, A: 1}
+	s.AddObject(testQuad(t, -1, -1, 1, 1, 0, flatMaterial("quad", color)))
+	s.RenderFrame(16, 16)
+
+	got := s.Renderer.ToImage().RGBAAt(8, 8)
+	for c, pair := range [][2]uint8{{got.R, color.R}, {got.G, color.G}, {got.B, color.B}} {
+		if diff := int(pair[0]) - int(pair[1]); diff < -1 || diff > 1 {
+			t.Errorf("channel %d is %d, want the surface's %d", c, pair[0], pair[1])
+		}
+	}
+}
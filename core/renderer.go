@@ -4,68 +4,172 @@ import (
 	"GopherEngine/assets"
 	"GopherEngine/lookdev"
 	"GopherEngine/nomath"
+	"fmt"
 	"image"
-	"image/color"
 	"image/png"
 	"math"
 	"os"
+	"strings"
 	"sync"
 )
 
+// Texture sampling modes
+const (
+	TextureModePerPixel = 0 // Perspective-correct UVs sampled for every fragment
+	TextureModeCentroid = 1 // One sample per triangle at its centroid (fast)
+)
+
+// Shading modes
+const (
+	ShadingFlat    = 0 // One face normal per triangle
+	ShadingGouraud = 1 // Lighting evaluated per vertex and interpolated
+	ShadingPhong   = 2 // Normals interpolated and lit per pixel
+)
+
+// Tone mapping operators, which bring the HDR frame into the displayable range
+const (
+	ToneMapExposure = 0 // Scale by the exposure and clip at white
+	ToneMapReinhard = 1 // Extended Reinhard on luminance, reaching white at WhitePoint
+	ToneMapACES     = 2 // Filmic curve fitted to the ACES reference transform
+)
+
+// ToneMapNames are the tone mapping operators' names, indexed by the constants above
+var ToneMapNames = []string{"Exposure", "Reinhard", "ACES"}
+
+// ToneMapName returns the name of a tone mapping operator
+func ToneMapName(operator int) string {
+	if operator < 0 || operator >= len(ToneMapNames) {
+		return fmt.Sprintf("unknown (%d)", operator)
+	}
+	return ToneMapNames[operator]
+}
+
+// ParseToneMap returns the tone mapping operator with the given name, in any case
+func ParseToneMap(name string) (int, bool) {
+	for operator, n := range ToneMapNames {
+		if strings.EqualFold(n, name) {
+			return operator, true
+		}
+	}
+	return 0, false
+}
+
 type Renderer3D struct {
-	Framebuffer          [][]lookdev.ColorRGBA // Changed to value type
-	DepthBuffer          [][]float32           // Changed to float32 for better cache usage
+	ColorBuffer          []float32 // Linear HDR RGB, row by row, three floats per pixel
+	DepthBuffer          []float32 // One depth per pixel, row by row; the nearest sample's with MSAA
 	BackFaceCulling      bool
+	TextureMode          int
+	ShadingMode          int
 	bufferMutex          sync.Mutex // For thread-safe resizing
 	precomputedLightDirs []nomath.Vec3
 	ambienceFactor       float64
 
-	CachedRGBA   []color.RGBA
-	cachedWidth  int
-	cachedHeight int
+	width  int // Size of the render targets, larger than the output when supersampling
+	height int
+
+	// Post-processing, run over each finished frame
+	PostEffects   []PostEffect // Applied in order to the HDR frame
+	FXAAEnabled   bool         // Fast approximate anti-aliasing, on the output image
+	Supersampling float64      // Render at this multiple of the output size and filter down; 1 disables
+
+	// The output image. Each frame is tone mapped and sRGB encoded into it.
+	Framebuffer []uint8 // Packed RGBA8, row by row, four bytes per pixel
+	ToneMapping int     // ToneMapExposure, ToneMapReinhard or ToneMapACES
+	Exposure    float64 // Scales the frame before tone mapping
+	WhitePoint  float64 // Luminance that Reinhard maps to white
+
+	outputWidth    int
+	outputHeight   int
+	image          *image.RGBA   // Wraps Framebuffer, so ToImage doesn't copy
+	outputColor    []float32     // The HDR frame at output size, ColorBuffer itself unless supersampling
+	downsampleX    [][]filterTap // Box filter from the render targets to the output, per column
+	downsampleY    [][]filterTap // and per row
+	downsampleRows []float32     // Frame filtered horizontally only
+	postScratch    []uint8       // Copy of the output for passes that read neighbouring pixels
+	postLuma       []float32
+
+	// Multisample anti-aliasing. Triangles are drawn into the sample buffers,
+	// which Resolve averages into ColorBuffer at the end of a frame.
+	MSAASamples int       // Samples per pixel: 1 (off), 2, 4 or 8
+	samples     int       // Sample count the buffers are allocated for
+	sampleColor []float32 // Linear HDR RGB, the samples of each pixel in turn
+	sampleDepth []float32 // One depth per sample
+
+	ShadowsEnabled bool // Global switch for shadow-casting lights
 
 	SSAOEnabled      bool
-	SSAOBuffer       [][]float32 // Stores ambient occlusion values
+	SSAOBuffer       []float32 // Stores ambient occlusion values, one per pixel
 	SSAOKernel       []nomath.Vec3
 	SSAORadius       float64
 	SSAOBias         float64
 	SSAOSamples      int
 	SSAONoiseTexture *lookdev.Texture
-	SSAONoiseScale   float64
+	SSAONoiseScale   float64 // Screen pixels per noise texel
 
-	rowLocks []sync.Mutex // NEW: One mutex per row
+	ssaoBlurred []float32
+	ssaoNormals []nomath.Vec3      // World-space normals of the visible fragments
+	ssaoAlbedo  []lookdev.ColorHDR // Linear diffuse colors, to take occluded ambient light back out
+
+	tiles   tileBins        // Screen tiles the triangles of a pass are binned into
+	arenas  []triangleArena // One per setup batch, reused every pass
+	workers int             // Goroutines for the per-pixel passes, set by each frame
 }
 
 func NewRenderer3D() *Renderer3D {
 	r := &Renderer3D{
 		BackFaceCulling: true,
-		Framebuffer:     make([][]lookdev.ColorRGBA, SCREEN_HEIGHT),
-		DepthBuffer:     make([][]float32, SCREEN_HEIGHT),
-		rowLocks:        make([]sync.Mutex, SCREEN_HEIGHT), // INIT ROW LOCKS
+		TextureMode:     TextureModePerPixel,
+		ShadingMode:     ShadingFlat,
+		ShadowsEnabled:  true,
 		ambienceFactor:  1.0,
+		SSAORadius:      2.0,
+		SSAOBias:        0.05,
+		SSAOSamples:     16,
+		SSAONoiseScale:  1.0,
+		MSAASamples:     1,
+		samples:         1,
+		Supersampling:   1.0,
+		workers:         1,
+		ToneMapping:     ToneMapExposure,
+		Exposure:        1.0,
+		WhitePoint:      11.0, // Ambience plus a directional light at its default intensity shining head-on
 	}
 	// Init buffers
-	for y := 0; y < SCREEN_HEIGHT; y++ {
-		r.Framebuffer[y] = make([]lookdev.ColorRGBA, SCREEN_WIDTH)
-		r.DepthBuffer[y] = make([]float32, SCREEN_WIDTH)
-		for x := 0; x < SCREEN_WIDTH; x++ {
-			r.DepthBuffer[y][x] = math.MaxFloat32
-		}
-	}
+	r.Resize(SCREEN_WIDTH, SCREEN_HEIGHT)
 	return r
 }
 
 func (r *Renderer3D) GetWidth() int {
-	if len(r.Framebuffer) == 0 {
-		return 0
-	}
-	return len(r.Framebuffer[0])
+	return r.width
 }
 
 func (r *Renderer3D) GetHeight() int {
-	return len(r.Framebuffer)
+	return r.height
+}
+
+// pixelIndex returns where pixel (x, y) is in DepthBuffer and the other
+// per-pixel buffers. Its color starts at three times that in ColorBuffer.
+func (r *Renderer3D) pixelIndex(x, y int) int {
+	return y*r.width + x
+}
+
+// setPixel writes an opaque color to the pixel at index i, and to all of its
+// samples with MSAA
+func (r *Renderer3D) setPixel(i int, color lookdev.ColorHDR) {
+	if r.samples > 1 {
+		for s := i * r.samples; s < (i+1)*r.samples; s++ {
+			p := r.sampleColor[s*3 : s*3+3 : s*3+3]
+			p[0], p[1], p[2] = color.R, color.G, color.B
+		}
+		return
+	}
+	p := r.ColorBuffer[i*3 : i*3+3 : i*3+3]
+	p[0], p[1], p[2] = color.R, color.G, color.B
 }
 
+// Resize sets the output resolution and reallocates the render targets,
+// which are Supersampling times larger. The buffers are kept as they are
+// when neither size changes.
 func (r *Renderer3D) Resize(width, height int) {
 	r.bufferMutex.Lock()
 	defer r.bufferMutex.Unlock()
@@ -73,55 +177,62 @@ func (r *Renderer3D) Resize(width, height int) {
 	// Ensure minimum size
 	width = max(1, width)
 	height = max(1, height)
-
-	// Create new framebuffer
-	newFramebuffer := make([][]lookdev.ColorRGBA, height)
-	for y := 0; y < height; y++ {
-		newFramebuffer[y] = make([]lookdev.ColorRGBA, width)
+	renderWidth, renderHeight := width, height
+	if r.Supersampling > 1 {
+		renderWidth = int(math.Round(float64(width) * r.Supersampling))
+		renderHeight = int(math.Round(float64(height) * r.Supersampling))
 	}
-
-	// Create new depth buffer
-	newDepthBuffer := make([][]float32, height)
-	for y := 0; y < height; y++ {
-		newDepthBuffer[y] = make([]float32, width)
-		for x := 0; x < width; x++ {
-			newDepthBuffer[y][x] = math.MaxFloat32
-		}
+	if width == r.outputWidth && height == r.outputHeight && renderWidth == r.width && renderHeight == r.height {
+		return
 	}
 
-	// Create new SSAO buffer if enabled
-	if r.SSAOEnabled {
-		r.SSAOBuffer = make([][]float32, height)
-		for y := 0; y < height; y++ {
-			r.SSAOBuffer[y] = make([]float32, width)
-		}
+	// SSAO buffers are reallocated by prepareSSAO when the size changes;
+	// free them while SSAO is off
+	if !r.SSAOEnabled {
+		r.SSAOBuffer, r.ssaoBlurred, r.ssaoNormals, r.ssaoAlbedo = nil, nil, nil, nil
 	}
 
-	// Update cached RGBA buffer
-	r.cachedWidth = width
-	r.cachedHeight = height
-	r.CachedRGBA = make([]color.RGBA, width*height)
-
-	// Atomic swap of buffers
-	r.Framebuffer = newFramebuffer
-	r.DepthBuffer = newDepthBuffer
+	r.width, r.height = renderWidth, renderHeight
+	r.ColorBuffer = make([]float32, renderWidth*renderHeight*3)
+	r.DepthBuffer = make([]float32, renderWidth*renderHeight)
+	for i := range r.DepthBuffer {
+		r.DepthBuffer[i] = math.MaxFloat32
+	}
+	r.allocateSamples()
 
-	r.rowLocks = make([]sync.Mutex, height) // When resizing
+	r.outputWidth, r.outputHeight = width, height
+	r.Framebuffer = make([]uint8, width*height*4)
+	r.postScratch, r.postLuma = nil, nil
+	r.outputColor = r.ColorBuffer
+	r.downsampleX, r.downsampleY, r.downsampleRows = nil, nil, nil
+	if renderWidth != width || renderHeight != height {
+		r.outputColor = make([]float32, width*height*3)
+		r.downsampleX = boxFilterTaps(renderWidth, width)
+		r.downsampleY = boxFilterTaps(renderHeight, height)
+		r.downsampleRows = make([]float32, width*renderHeight*3)
+	}
+	r.image = &image.RGBA{Pix: r.Framebuffer, Stride: width * 4, Rect: image.Rect(0, 0, width, height)}
 }
 
 func (r *Renderer3D) Clear(color lookdev.ColorRGBA) {
-	// Use the actual renderer dimensions, not SCREEN_WIDTH/HEIGHT
-	width := r.GetWidth()
-	height := r.GetHeight()
+	// Pick up changes to Supersampling and MSAASamples
+	r.Resize(r.outputWidth, r.outputHeight)
+	r.prepareMSAA()
 
-	for y := 0; y < height; y++ {
-		rowPixels := r.Framebuffer[y]
-		rowDepth := r.DepthBuffer[y]
-
-		for x := 0; x < width; x++ {
-			rowPixels[x] = color
-			rowDepth[x] = math.MaxFloat32
-		}
+	// Fill the first row, then copy it down
+	linear := color.Linear()
+	row := r.ColorBuffer[:r.width*3]
+	for i := 0; i < len(row); i += 3 {
+		row[i], row[i+1], row[i+2] = linear.R, linear.G, linear.B
+	}
+	for y := 1; y < r.height; y++ {
+		copy(r.ColorBuffer[y*len(row):], row)
+	}
+	for i := range r.DepthBuffer {
+		r.DepthBuffer[i] = math.MaxFloat32
+	}
+	if r.samples > 1 {
+		r.expandSamples()
 	}
 }
 
@@ -129,20 +240,12 @@ type DirtyRect struct {
 	X1, Y1, X2, Y2 int
 }
 
+// ToImage returns the output image without copying it, so it is overwritten
+// by the next frame. Copy it to keep a frame around. Rendering a scene fills
+// it in at the end, after resolving MSAA, post-processing, filtering a
+// supersampled frame down to the output size and tone mapping.
 func (r *Renderer3D) ToImage() *image.RGBA {
-	img := image.NewRGBA(image.Rect(0, 0, r.GetWidth(), r.GetHeight()))
-	for y := 0; y < r.GetHeight(); y++ {
-		for x := 0; x < r.GetWidth(); x++ {
-			c := r.Framebuffer[y][x]
-			img.SetRGBA(x, y, color.RGBA{
-				R: c.R,
-				G: c.G,
-				B: c.B,
-				A: 255,
-			})
-		}
-	}
-	return img
+	return r.image
 }
 
 func (r *Renderer3D) SaveToPNG(filename string) error {
@@ -171,56 +274,17 @@ func (r *Renderer3D) NDCToScreen(ndc nomath.Vec3) (int, int) {
 
 func (r *Renderer3D) DrawLine3D(p0, p1 nomath.Vec3, camera *PerspectiveCamera, color *lookdev.ColorRGBA) {
 	// Precompute matrices once
-	viewMatrix := camera.GetViewMatrix()
-	projectionMatrix := camera.GetProjectionMatrix()
-	nearPlane := camera.NearPlane
-
-	// Transform points
-	viewP0 := viewMatrix.MultiplyVec4(p0.ToVec4(1.0))
-	viewP1 := viewMatrix.MultiplyVec4(p1.ToVec4(1.0))
-	clip0 := projectionMatrix.MultiplyVec4(viewP0)
-	clip1 := projectionMatrix.MultiplyVec4(viewP1)
-
-	// Early rejection
-	if clip0.W <= 0 && clip1.W <= 0 {
-		return
-	}
-
-	// Handle perspective division with clipping
-	var ndc0, ndc1 nomath.Vec3
-	if clip0.W > 0 {
-		ndc0 = clip0.ToVec3()
-	} else {
-		t := (nearPlane - viewP0.Z) / (viewP1.Z - viewP0.Z)
-		ndc0 = nomath.Vec3{
-			X: (viewP0.X + t*(viewP1.X-viewP0.X)) / nearPlane,
-			Y: (viewP0.Y + t*(viewP1.Y-viewP0.Y)) / nearPlane,
-			Z: 1.0,
-		}
-	}
-
-	if clip1.W > 0 {
-		ndc1 = clip1.ToVec3()
-	} else {
-		t := (nearPlane - viewP0.Z) / (viewP1.Z - viewP0.Z)
-		ndc1 = nomath.Vec3{
-			X: (viewP0.X + t*(viewP1.X-viewP0.X)) / nearPlane,
-			Y: (viewP0.Y + t*(viewP1.Y-viewP0.Y)) / nearPlane,
-			Z: 1.0,
-		}
-	}
-
-	// Convert to screen coordinates
-	x0, y0 := r.NDCToScreen(ndc0)
-	x1, y1 := r.NDCToScreen(ndc1)
+	viewProj := camera.GetProjectionMatrix().Multiply(camera.GetViewMatrix())
 
-	// Early exit if line is completely off-screen
-	if (x0 < 0 && x1 < 0) || (x0 >= SCREEN_WIDTH && x1 >= SCREEN_WIDTH) ||
-		(y0 < 0 && y1 < 0) || (y0 >= SCREEN_HEIGHT && y1 >= SCREEN_HEIGHT) {
+	// Clip in homogeneous space so points behind the camera don't flip over
+	clip0, clip1, ok := clipLine(viewProj.MultiplyVec4(p0.ToVec4(1.0)), viewProj.MultiplyVec4(p1.ToVec4(1.0)))
+	if !ok {
 		return
 	}
 
-	// Draw the line
+	// Convert to screen coordinates and draw the line
+	x0, y0 := r.NDCToScreen(clip0.ToVec3())
+	x1, y1 := r.NDCToScreen(clip1.ToVec3())
 	r.DrawLine2D(x0, y0, x1, y1, color)
 }
 
@@ -238,7 +302,7 @@ func (r *Renderer3D) DrawLine2D(x0, y0, x1, y1 int, color *lookdev.ColorRGBA) {
 	// Early exit if points are the same
 	if x0 == x1 && y0 == y1 {
 		if x0 >= 0 && x0 < width && y0 >= 0 && y0 < height {
-			r.Framebuffer[y0][x0] = *color
+			r.setPixel(r.pixelIndex(x0, y0), color.Linear())
 		}
 		return
 	}
@@ -256,11 +320,12 @@ func (r *Renderer3D) DrawLine2D(x0, y0, x1, y1 int, color *lookdev.ColorRGBA) {
 
 	err := dx - dy
 	maxIterations := dx + dy + 1
+	linear := color.Linear()
 
 	for i := 0; i < maxIterations; i++ {
 		// Check bounds using actual dimensions
 		if x0 >= 0 && x0 < width && y0 >= 0 && y0 < height {
-			r.safeSetPixel(x0, y0, *color)
+			r.safeSetPixel(x0, y0, linear)
 		}
 
 		if x0 == x1 && y0 == y1 {
@@ -287,192 +352,406 @@ func abs(x int) int {
 	return x
 }
 
+// PreComputeLightDirs caches each light's direction (toward the light) for the
+// frame, so spot light axes are not rebuilt from Euler angles per fragment.
 func (r *Renderer3D) PreComputeLightDirs(s *Scene) {
-	r.precomputedLightDirs = make([]nomath.Vec3, len(s.Lights))
+	if len(r.precomputedLightDirs) != len(s.Lights) {
+		r.precomputedLightDirs = make([]nomath.Vec3, len(s.Lights))
+	}
 	for i, light := range s.Lights {
-		if light.Transform.Dirty {
-			if light.Type == LightTypeDirectional {
-				r.precomputedLightDirs[i] = light.Transform.Position.Normalize().Negate()
-			} else {
-				r.precomputedLightDirs[i] = light.Transform.Position
-			}
-		}
+		r.precomputedLightDirs[i] = light.GetDirection()
+	}
+}
+
+// lightDirection returns the cached direction of lights[i], falling back to
+// computing it when the light list changed since the last precompute.
+func (r *Renderer3D) lightDirection(i int, lights []*Light) nomath.Vec3 {
+	if len(r.precomputedLightDirs) == len(lights) {
+		return r.precomputedLightDirs[i]
 	}
+	return lights[i].GetDirection()
 }
-func (r *Renderer3D) RenderTriangle(mvpMatrix *nomath.Mat4, camera *PerspectiveCamera, tri *assets.Triangle, lights []*Light, scene *Scene) {
-	nearPlane := camera.NearPlane
 
-	// Transform vertices to clip space
-	v0 := mvpMatrix.MultiplyVec4(tri.V0.ToVec4(1.0))
-	v1 := mvpMatrix.MultiplyVec4(tri.V1.ToVec4(1.0))
-	v2 := mvpMatrix.MultiplyVec4(tri.V2.ToVec4(1.0))
+// triangleSetup holds the per-triangle data shared by all of its fragments
+type triangleSetup struct {
+	tri         *assets.Triangle
+	worldPos    [3]nomath.Vec3
+	worldNormal [3]nomath.Vec3
+	centroid    nomath.Vec3   // World-space centroid
+	vertexLight [3]lightTerms // Only filled for Gouraud shading
+	receives    bool          // Whether shadow maps apply to this triangle
+	blend       bool          // Blend into the framebuffer instead of writing depth
+
+	// World-space tangent frames, only filled when the material has a normal map
+	normalMapped   bool
+	worldTangent   [3]nomath.Vec3
+	worldBitangent [3]nomath.Vec3
+
+	// Gouraud terms of shadow-casting lights, kept apart so the shadow test
+	// can still be done per pixel
+	shadowedLights []shadowedVertexLight
+}
 
-	// Store in array for easier indexing
-	clipVerts := [3]nomath.Vec4{v0, v1, v2}
-	screenVerts := [3]nomath.Vec3{}
+type shadowedVertexLight struct {
+	light *Light
+	terms [3]lightTerms
+}
 
-	// Count how many vertices are in front of the near plane
-	inFront := [3]bool{}
-	numInFront := 0
-	for i := 0; i < 3; i++ {
-		if clipVerts[i].Z > -nearPlane {
-			inFront[i] = true
-			numInFront++
-		}
+// RenderTriangle draws a single triangle straight away. Scenes go through
+// renderTasks instead, which spreads the work over screen tiles.
+func (r *Renderer3D) RenderTriangle(task *RenderTask, camera *PerspectiveCamera, lights []*Light, scene *Scene) {
+	var arena triangleArena
+	r.prepareTriangle(task, camera, lights, &arena)
+	for _, st := range arena.out {
+		r.rasterizeTriangle(st, st.bounds, lights, camera)
+	}
+}
+
+// prepareTriangle sets up, clips and projects the task's triangle, adding the
+// screen triangles that are left to arena.out.
+func (r *Renderer3D) prepareTriangle(task *RenderTask, camera *PerspectiveCamera, lights []*Light, arena *triangleArena) {
+	tri := task.Triangle
+
+	// The vertex stage already moved the vertices to clip space
+	clip := task.Vertices.Clip
+	clipVerts := [3]clipVertex{
+		{Position: clip[tri.Indices[0]], Weights: nomath.Vec3{X: 1}},
+		{Position: clip[tri.Indices[1]], Weights: nomath.Vec3{Y: 1}},
+		{Position: clip[tri.Indices[2]], Weights: nomath.Vec3{Z: 1}},
 	}
 
-	// If all behind, skip
-	if numInFront == 0 {
+	// Skip triangles entirely outside one of the frustum planes
+	codes := [3]uint8{outcode(clipVerts[0].Position), outcode(clipVerts[1].Position), outcode(clipVerts[2].Position)}
+	if codes[0]&codes[1]&codes[2] != 0 {
 		return
 	}
 
-	// If all in front, proceed with regular rasterization
-	if numInFront == 3 {
-		for i := 0; i < 3; i++ {
-			screenVerts[i] = clipVerts[i].ToVec3()
+	setup := arena.newSetup()
+	r.setupTriangle(task, camera, lights, setup)
+
+	// If all inside, proceed with regular rasterization
+	crossed := codes[0] | codes[1] | codes[2]
+	if crossed == 0 {
+		if st, ok := r.projectTriangle(clipVerts, setup); ok {
+			arena.addScreen(st)
 		}
-		r.rasterizeTriangle(screenVerts, tri, lights, camera)
 		return
 	}
 
-	// Otherwise, clip against near plane and reconstruct 1 or 2 triangles
-	var newVerts []nomath.Vec3
-
-	getIntersect := func(a, b nomath.Vec4) nomath.Vec3 {
-		t := (-nearPlane - a.Z) / (b.Z - a.Z)
-		interp := a.Add(b.Sub(a).Multiply(t))
-		return interp.Divide(interp.W).ToVec3()
+	// Otherwise clip against the planes it crosses and fan out the polygon
+	polygon := clipTriangle(clipVerts, crossed)
+	for i := 1; i+1 < len(polygon); i++ {
+		if st, ok := r.projectTriangle([3]clipVertex{polygon[0], polygon[i], polygon[i+1]}, setup); ok {
+			arena.addScreen(st)
+		}
 	}
+}
 
-	for i := 0; i < 3; i++ {
-		curr := clipVerts[i]
-		next := clipVerts[(i+1)%3]
+// setupTriangle fills setup with the triangle's world-space vertex attributes
+// from the vertex stage and, for Gouraud shading, lights its vertices.
+func (r *Renderer3D) setupTriangle(task *RenderTask, camera *PerspectiveCamera, lights []*Light, setup *triangleSetup) {
+	tri := task.Triangle
+	*setup = triangleSetup{tri: tri, blend: task.Transparent, shadowedLights: setup.shadowedLights[:0]}
+	vertices := task.Vertices
+	setup.centroid = vertices.centroid(tri)
+	setup.receives = tri.Parent.ReceiveShadows && r.anyShadowMaps(lights)
 
-		currIn := inFront[i]
-		nextIn := inFront[(i+1)%3]
+	for i, idx := range tri.Indices {
+		setup.worldPos[i] = vertices.World[idx]
+	}
+	if r.ShadingMode == ShadingFlat {
+		return
+	}
 
-		if currIn {
-			// Keep current vertex
-			newVerts = append(newVerts, curr.ToVec3())
-		}
-		if currIn != nextIn {
-			// Edge crosses near plane — compute intersection
-			newVerts = append(newVerts, getIntersect(curr, next))
+	for i, idx := range tri.Indices {
+		if normal := vertices.Normals[idx]; normal != (nomath.Vec3{}) {
+			setup.worldNormal[i] = normal
+		} else {
+			setup.worldNormal[i] = tri.WorldNormal
 		}
 	}
 
-	// Rasterize new triangle(s)
-	if len(newVerts) < 3 {
-		return // degenerate
+	// Normal maps change the normal per pixel, so those triangles are always
+	// lit per pixel rather than per vertex
+	if tri.Material.NormalTexture != nil && tri.HasTangents() {
+		setup.normalMapped = true
+		for i, idx := range tri.Indices {
+			setup.worldTangent[i] = vertices.Tangents[idx]
+			setup.worldBitangent[i] = vertices.Bitangents[idx]
+		}
+		return
 	}
-	if len(newVerts) == 3 {
-		r.rasterizeTriangle([3]nomath.Vec3{newVerts[0], newVerts[1], newVerts[2]}, tri, lights, camera)
-	} else if len(newVerts) == 4 {
-		// Split quad into 2 triangles
-		r.rasterizeTriangle([3]nomath.Vec3{newVerts[0], newVerts[1], newVerts[2]}, tri, lights, camera)
-		r.rasterizeTriangle([3]nomath.Vec3{newVerts[0], newVerts[2], newVerts[3]}, tri, lights, camera)
+
+	if r.ShadingMode == ShadingGouraud {
+		eye := camera.Transform.Position
+		var viewDirs [3]nomath.Vec3
+		for i := 0; i < 3; i++ {
+			viewDirs[i] = eye.Subtract(setup.worldPos[i]).Normalize()
+		}
+		for l, light := range lights {
+			var terms [3]lightTerms
+			for i := 0; i < 3; i++ {
+				terms[i] = r.evaluateLight(light, r.lightDirection(l, lights), setup.worldPos[i], setup.worldNormal[i], viewDirs[i], tri.Material.Shininess, false)
+			}
+			if setup.receives && r.hasShadowMap(light) {
+				setup.shadowedLights = append(setup.shadowedLights, shadowedVertexLight{light: light, terms: terms})
+				continue
+			}
+			for i := 0; i < 3; i++ {
+				setup.vertexLight[i] = setup.vertexLight[i].add(terms[i])
+			}
+		}
 	}
 }
 
-func (r *Renderer3D) rasterizeTriangle(verts [3]nomath.Vec3, tri *assets.Triangle, lights []*Light, camera *PerspectiveCamera) {
-	x0, y0 := r.NDCToScreen(verts[0])
-	x1, y1 := r.NDCToScreen(verts[1])
-	x2, y2 := r.NDCToScreen(verts[2])
+// screenTriangle is a clipped triangle projected onto the screen, ready to
+// be rasterized one tile at a time
+type screenTriangle struct {
+	clipVerts [3]clipVertex
+	invW      [3]float64
+	depth     [3]float64
+	edges     [3]edgeEquation // Opposite each vertex, giving its barycentric weight
+	invArea   float64         // Scales edge values to barycentric weights
+	bounds    image.Rectangle // Covered pixels, clamped to the screen and inclusive of Max
+	setup     *triangleSetup
+}
 
-	minX := max(0, min(x0, min(x1, x2)))
-	maxX := min(r.GetWidth()-1, max(x0, max(x1, x2)))
-	minY := max(0, min(y0, min(y1, y2)))
-	maxY := min(r.GetHeight()-1, max(y0, max(y1, y2)))
+// projectTriangle moves a clipped triangle to screen space. It reports false
+// when the triangle covers no pixels.
+func (r *Renderer3D) projectTriangle(clipVerts [3]clipVertex, setup *triangleSetup) (screenTriangle, bool) {
+	st := screenTriangle{clipVerts: clipVerts, setup: setup}
+	width, height := float64(r.GetWidth()), float64(r.GetHeight())
+	var xs, ys [3]int64
+	for i := 0; i < 3; i++ {
+		ndc := clipVerts[i].Position.ToVec3()
+		st.invW[i] = 1.0
+		if clipVerts[i].Position.W != 0 {
+			st.invW[i] = 1.0 / clipVerts[i].Position.W
+		}
+		xs[i] = toSubpixel((ndc.X + 1) * 0.5 * width)
+		ys[i] = toSubpixel((1 - (ndc.Y+1)*0.5) * height)
+		st.depth[i] = (ndc.Z + 1) * 0.5
+	}
+
+	// Wind every triangle the same way so the edge functions are positive inside
+	area := (xs[1]-xs[0])*(ys[2]-ys[0]) - (ys[1]-ys[0])*(xs[2]-xs[0])
+	if area == 0 {
+		return st, false
+	}
+	if area < 0 {
+		area = -area
+		xs[1], xs[2] = xs[2], xs[1]
+		ys[1], ys[2] = ys[2], ys[1]
+		st.clipVerts[1], st.clipVerts[2] = st.clipVerts[2], st.clipVerts[1]
+		st.invW[1], st.invW[2] = st.invW[2], st.invW[1]
+		st.depth[1], st.depth[2] = st.depth[2], st.depth[1]
+	}
+	st.invArea = 1.0 / float64(area)
+	st.edges = [3]edgeEquation{
+		newEdgeEquation(xs[1], ys[1], xs[2], ys[2]),
+		newEdgeEquation(xs[2], ys[2], xs[0], ys[0]),
+		newEdgeEquation(xs[0], ys[0], xs[1], ys[1]),
+	}
+
+	// Pixels whose centres fall within the vertex extents, or with MSAA,
+	// whose samples might
+	firstPixel := func(v int64) int { return int((v - subpixelHalf + subpixelOne - 1) >> subpixelBits) }
+	lastPixel := func(v int64) int { return int((v - subpixelHalf) >> subpixelBits) }
+	if r.samples > 1 {
+		firstPixel = func(v int64) int { return int(v >> subpixelBits) }
+		lastPixel = firstPixel
+	}
+	// Not image.Rect, which would swap the corners of an empty box
+	st.bounds = image.Rectangle{
+		Min: image.Pt(
+			max(0, firstPixel(min(xs[0], xs[1], xs[2]))),
+			max(0, firstPixel(min(ys[0], ys[1], ys[2]))),
+		),
+		Max: image.Pt(
+			min(r.GetWidth()-1, lastPixel(max(xs[0], xs[1], xs[2]))),
+			min(r.GetHeight()-1, lastPixel(max(ys[0], ys[1], ys[2]))),
+		),
+	}
+	if st.bounds.Min.X > st.bounds.Max.X || st.bounds.Min.Y > st.bounds.Max.Y {
+		return st, false
+	}
+	return st, true
+}
 
+// rasterizeTriangle shades the pixels of st inside area, whose Max is
+// inclusive. Callers give each pixel to one goroutine at a time, so depth
+// tests and writes need no locking.
+func (r *Renderer3D) rasterizeTriangle(st *screenTriangle, area image.Rectangle, lights []*Light, camera *PerspectiveCamera) {
+	minX, minY := max(area.Min.X, st.bounds.Min.X), max(area.Min.Y, st.bounds.Min.Y)
+	maxX, maxY := min(area.Max.X, st.bounds.Max.X), min(area.Max.Y, st.bounds.Max.Y)
 	if minX > maxX || minY > maxY {
 		return
 	}
+	shader := r.newFragmentShader(st, lights, camera)
+	if r.samples > 1 {
+		r.rasterizeSamples(st, image.Rect(minX, minY, maxX, maxY), &shader)
+		return
+	}
+
+	depth0, depth1, depth2 := st.depth[0], st.depth[1], st.depth[2]
+	e0, e1, e2 := st.edges[0], st.edges[1], st.edges[2]
 
-	v0Screen := nomath.Vec2{U: float64(x0), V: float64(y0)}
-	v1Screen := nomath.Vec2{U: float64(x1), V: float64(y1)}
-	v2Screen := nomath.Vec2{U: float64(x2), V: float64(y2)}
-
-	depth0 := (verts[0].Z + 1) * 0.5
-	depth1 := (verts[1].Z + 1) * 0.5
-	depth2 := (verts[2].Z + 1) * 0.5
-
-	for y := minY; y <= maxY; y++ {
-		for x := minX; x <= maxX; x++ {
-			p := nomath.Vec2{U: float64(x), V: float64(y)}
-			u, v, w := tri.Barycentric(p, v0Screen, v1Screen, v2Screen)
-
-			if u >= 0 && v >= 0 && w >= 0 {
-				depth := u*depth0 + v*depth1 + w*depth2
-				if depth >= 0 && depth <= 1 && depth < float64(r.DepthBuffer[y][x]) {
-					var color *lookdev.ColorRGBA
-					if len(tri.LightDotNormals) == len(lights) {
-						color = r.calculateLightingWithPrecomputed(tri, lights)
-					} else {
-						color = r.calculateLighting(tri, tri.WorldNormal, camera.Transform.GetForward(), lights)
-					}
-					r.safeSetPixel(x, y, *color)
-					r.DepthBuffer[y][x] = float32(depth)
-				}
+	// Step the edge functions across the box rather than evaluating them
+	// at every pixel
+	row0, row1, row2 := e0.at(minX, minY), e1.at(minX, minY), e2.at(minX, minY)
+	for y := minY; y <= maxY; y, row0, row1, row2 = y+1, row0+e0.stepY, row1+e1.stepY, row2+e2.stepY {
+		w0, w1, w2 := row0, row1, row2
+		for x := minX; x <= maxX; x, w0, w1, w2 = x+1, w0+e0.stepX, w1+e1.stepX, w2+e2.stepX {
+			if w0|w1|w2 < 0 {
+				continue
+			}
+			u := float64(w0-e0.bias) * st.invArea
+			v := float64(w1-e1.bias) * st.invArea
+			w := float64(w2-e2.bias) * st.invArea
+
+			depth := u*depth0 + v*depth1 + w*depth2
+			i := r.pixelIndex(x, y)
+			if depth < 0 || depth > 1 || depth >= float64(r.DepthBuffer[i]) {
+				continue
+			}
+			color, diffuse, weights, ok := shader.shade(u, v, w)
+			if !ok {
+				continue
+			}
+			if st.setup.blend {
+				// Depth tested against the opaque pass but never written,
+				// so surfaces behind still show through
+				r.blendPixel(i, color, shader.opacity(diffuse, weights))
+				continue
+			}
+
+			r.setPixel(i, color)
+			r.DepthBuffer[i] = float32(depth)
+			if shader.writeSSAO {
+				r.writeSSAOSample(i, shader.normal(weights), diffuse)
 			}
 		}
 	}
 }
 
-func (r *Renderer3D) calculateLightingWithPrecomputed(tri *assets.Triangle, lights []*Light) *lookdev.ColorRGBA {
-	result := *tri.DiffuseBuffer
+// fragmentShader lights the fragments of one screen triangle
+type fragmentShader struct {
+	r      *Renderer3D
+	st     *screenTriangle
+	lights []*Light
+	camera *PerspectiveCamera
+	eye    nomath.Vec3
+
+	perPixel     bool // Textures are sampled per fragment
+	alphaTexture bool // Opacity comes from the transparency texture
+	alphaTested  bool
+	vertexColors bool
+	needWeights  bool // Perspective-correct weights are used at all
+	writeSSAO    bool // Fragments feed the SSAO buffers
+}
 
-	// Apply precomputed lighting factors
-	for i, dot := range tri.LightDotNormals {
-		if i >= len(lights) {
-			break
+func (r *Renderer3D) newFragmentShader(st *screenTriangle, lights []*Light, camera *PerspectiveCamera) fragmentShader {
+	setup := st.setup
+	tri := setup.tri
+
+	// Textures are only sampled per fragment when there is something to sample
+	perPixel := r.TextureMode == TextureModePerPixel &&
+		(tri.Material.DiffuseTexture != nil || tri.Material.SpecularTexture != nil)
+	f := fragmentShader{
+		r:            r,
+		st:           st,
+		lights:       lights,
+		camera:       camera,
+		eye:          camera.Transform.Position,
+		perPixel:     perPixel,
+		alphaTexture: setup.blend && tri.Material.TransparencyTexture != nil,
+		alphaTested:  tri.Material.IsAlphaTested(),
+		vertexColors: tri.HasVertexColors(),
+		writeSSAO:    !setup.blend && r.SSAOEnabled && len(r.ssaoNormals) == len(r.DepthBuffer),
+	}
+	f.needWeights = f.perPixel || f.alphaTexture || f.alphaTested || f.vertexColors || r.ShadingMode != ShadingFlat || setup.receives
+	return f
+}
+
+// shade returns the color of the fragment at screen-space weights u, v and w
+// along with its diffuse color and perspective-correct weights. ok is false
+// when the alpha test cuts it out.
+func (f *fragmentShader) shade(u, v, w float64) (color lookdev.ColorHDR, diffuse *lookdev.ColorRGBA, weights nomath.Vec3, ok bool) {
+	r, setup, lights := f.r, f.st.setup, f.lights
+	tri := setup.tri
+
+	diffuse, specular := tri.DiffuseBuffer, tri.SpecularBuffer
+	if f.needWeights {
+		// Screen-space weights become perspective-correct once divided by w
+		weights = perspectiveWeights(f.st.clipVerts, f.st.invW, u, v, w)
+	}
+	if f.alphaTested {
+		uv := tri.InterpolatedUV(weights.X, weights.Y, weights.Z)
+		if tri.Material.IsCutout(uv.U, uv.V) {
+			return color, nil, weights, false
 		}
-		intensity := float64(lights[i].Intensity) / 255.0
-		result.R = min(255, result.R+uint8(float64(tri.DiffuseBuffer.R)*dot*intensity))
-		result.G = min(255, result.G+uint8(float64(tri.DiffuseBuffer.G)*dot*intensity))
-		result.B = min(255, result.B+uint8(float64(tri.DiffuseBuffer.B)*dot*intensity))
 	}
-
-	// Apply specular if available
-	if tri.SpecularBuffer != nil {
-		result.R = min(255, result.R+tri.SpecularBuffer.R)
-		result.G = min(255, result.G+tri.SpecularBuffer.G)
-		result.B = min(255, result.B+tri.SpecularBuffer.B)
+	if f.perPixel {
+		diffuse, specular = sampleSurface(tri, weights)
+	}
+	if f.vertexColors {
+		diffuse = tintByVertexColor(diffuse, tri, weights)
+	}
+
+	switch {
+	case setup.normalMapped:
+		normal := setup.mappedNormal(weights)
+		position := interpolateVec3(setup.worldPos, weights)
+		viewDir := f.eye.Subtract(position).Normalize()
+		color = r.calculateLighting(diffuse, specular, tri, position, normal, viewDir, lights, setup.receives)
+	case r.ShadingMode == ShadingGouraud:
+		terms := interpolateLightTerms(setup.vertexLight, weights)
+		if len(setup.shadowedLights) > 0 {
+			position := interpolateVec3(setup.worldPos, weights)
+			for _, shadowed := range setup.shadowedLights {
+				lit := interpolateLightTerms(shadowed.terms, weights)
+				terms = terms.add(lit.scale(r.shadowFactor(shadowed.light, position)))
+			}
+		}
+		color = r.shade(diffuse, specular, terms)
+	case r.ShadingMode == ShadingPhong:
+		normal := interpolateVec3(setup.worldNormal, weights).Normalize()
+		position := interpolateVec3(setup.worldPos, weights)
+		viewDir := f.eye.Subtract(position).Normalize()
+		color = r.calculateLighting(diffuse, specular, tri, position, normal, viewDir, lights, setup.receives)
+	case len(tri.LightDotNormals) == len(lights):
+		position := setup.centroid
+		if setup.receives {
+			position = interpolateVec3(setup.worldPos, weights)
+		}
+		color = r.calculateLightingWithPrecomputed(diffuse, specular, tri, position, lights, setup.receives)
+	default:
+		color = r.calculateLighting(diffuse, specular, tri, setup.centroid, tri.WorldNormal, f.camera.Transform.GetForward().Negate(), lights, setup.receives)
 	}
-
-	return &result
+	return color, diffuse, weights, true
 }
 
-func (r *Renderer3D) calculateLighting(tri *assets.Triangle, normal nomath.Vec3, viewDir nomath.Vec3, lights []*Light) *lookdev.ColorRGBA {
-	result := *tri.DiffuseBuffer
-
-	for _, light := range lights {
-		lightDir := light.GetDirection()
-		diffuseFactor := math.Max(0, normal.Dot(lightDir))
-		intensity := float64(light.Intensity) / 255.0
-
-		result.R = min(255, result.R+uint8(float64(tri.DiffuseBuffer.R)*diffuseFactor*intensity))
-		result.G = min(255, result.G+uint8(float64(tri.DiffuseBuffer.G)*diffuseFactor*intensity))
-		result.B = min(255, result.B+uint8(float64(tri.DiffuseBuffer.B)*diffuseFactor*intensity))
-
-		// Specular (Blinn-Phong)
-		halfDir := lightDir.Add(viewDir).Normalize()
-		specFactor := math.Pow(math.Max(0, normal.Dot(halfDir)), float64(tri.Material.Shininess))
-		if tri.SpecularBuffer != nil {
-			result.R = min(255, result.R+uint8(float64(tri.SpecularBuffer.R)*specFactor*intensity))
-			result.G = min(255, result.G+uint8(float64(tri.SpecularBuffer.G)*specFactor*intensity))
-			result.B = min(255, result.B+uint8(float64(tri.SpecularBuffer.B)*specFactor*intensity))
-		}
+// opacity returns how much of a blended fragment covers what is behind it
+func (f *fragmentShader) opacity(diffuse *lookdev.ColorRGBA, weights nomath.Vec3) float64 {
+	tri := f.st.setup.tri
+	var uv nomath.Vec2
+	if f.alphaTexture {
+		uv = tri.InterpolatedUV(weights.X, weights.Y, weights.Z)
 	}
+	return tri.Material.Opacity(diffuse.A, uv.U, uv.V)
+}
 
-	return &result
+// normal returns the world-space normal stored for SSAO
+func (f *fragmentShader) normal(weights nomath.Vec3) nomath.Vec3 {
+	if f.r.ShadingMode == ShadingFlat {
+		return f.st.setup.tri.WorldNormal
+	}
+	return interpolateVec3(f.st.setup.worldNormal, weights)
 }
 
-func (r *Renderer3D) safeSetPixel(x, y int, color lookdev.ColorRGBA) {
+func (r *Renderer3D) safeSetPixel(x, y int, color lookdev.ColorHDR) {
 	if x < 0 || x >= r.GetWidth() || y < 0 || y >= r.GetHeight() {
 		return
 	}
-
-	r.rowLocks[y].Lock()
-	r.Framebuffer[y][x] = color
-	r.rowLocks[y].Unlock()
+	r.setPixel(r.pixelIndex(x, y), color)
 }
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"math"
+	"testing"
+)
+
+func TestPerspectiveWeights(t *testing.T) {
+	corners := [3]clipVertex{
+		{Weights: nomath.Vec3{X: 1}},
+		{Weights: nomath.Vec3{Y: 1}},
+		{Weights: nomath.Vec3{Z: 1}},
+	}
+	// A vertex clipped off halfway along the edge from the first to the
+	// second corner of the source triangle
+	clipped := [3]clipVertex{
+		{Weights: nomath.Vec3{X: 0.5, Y: 0.5}},
+		{Weights: nomath.Vec3{Y: 1}},
+		{Weights: nomath.Vec3{Z: 1}},
+	}
+	tests := []struct {
+		name    string
+		verts   [3]clipVertex
+		w       [3]float64
+		u, v, s float64
+		want    nomath.Vec3
+	}{
+		{"same depth", corners, [3]float64{2, 2, 2}, 0.5, 0.5, 0, nomath.Vec3{X: 0.5, Y: 0.5}},
+		// Halfway across the screen is a quarter of the way to a vertex
+		// three times further away
+		{"receding edge", corners, [3]float64{1, 3, 1}, 0.5, 0.5, 0, nomath.Vec3{X: 0.75, Y: 0.25}},
+		{"at a corner", corners, [3]float64{1, 3, 5}, 0, 0, 1, nomath.Vec3{Z: 1}},
+		{"clipped", clipped, [3]float64{1, 1, 1}, 1, 0, 0, nomath.Vec3{X: 0.5, Y: 0.5}},
+		{"clipped and receding", clipped, [3]float64{1, 3, 1}, 0.5, 0.5, 0, nomath.Vec3{X: 0.375, Y: 0.625}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invW := [3]float64{1 / tt.w[0], 1 / tt.w[1], 1 / tt.w[2]}
+			got := perspectiveWeights(tt.verts, invW, tt.u, tt.v, tt.s)
+			if got.Subtract(tt.want).Length() > 1e-12 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if sum := got.X + got.Y + got.Z; math.Abs(sum-1) > 1e-12 {
+				t.Errorf("weights sum to %v", sum)
+			}
+		})
+	}
+}
+
+// cutoutTexture is 2x1, see-through on the left and opaque red on the right
+func cutoutTexture() *lookdev.Texture {
+	return &lookdev.Texture{
+		Width:    2,
+		Height:   1,
+		Pixels:   []lookdev.ColorRGBA{{R: 255, A: 0.2}, {R: 255, A: 1}},
+		HasAlpha: true,
+	}
+}
+
+func TestAlphaCutout(t *testing.T) {
+	leaf := flatMaterial("leaf", lookdev.ColorRGBA{R: 255, G: 255, B: 255, A: 1})
+	leaf.DiffuseTexture = cutoutTexture()
+	leaf.AlphaCutoff = 0.5
+	if leaf.IsTransparent() {
+		t.Fatal("an alpha-tested material is blended")
+	}
+
+	s := newTestScene()
+	s.AddObject(testQuad(t, -1, -1, 1, 1, 0, leaf))
+	s.AddObject(testQuad(t, -2, -2, 2, 2, -1, flatMaterial("green", lookdev.ColorRGBA{G: 255, A: 1})))
+	s.RenderFrame(64, 64)
+	img := s.Renderer.ToImage()
+
+	// The quad spans about 15 pixels either side of the center
+	if got := img.RGBAAt(24, 32); got.G == 0 || got.R != 0 {
+		t.Errorf("cut out texel shows %v, want the green behind it", got)
+	}
+	if got := img.RGBAAt(40, 32); got.R == 0 || got.G != 0 {
+		t.Errorf("opaque texel shows %v, want red", got)
+	}
+}
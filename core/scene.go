@@ -2,7 +2,9 @@ package core
 
 import (
 	"GopherEngine/assets"
+	"GopherEngine/lookdev"
 	"GopherEngine/nomath"
+	"log"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -12,12 +14,9 @@ var SCREEN_WIDTH int = 854
 var SCREEN_HEIGHT int = 480
 
 type RenderTask struct {
-	Triangle *assets.Triangle
-	MVP      nomath.Mat4
-	// Add any other frequently used precomputed data here
-	NormalMatrix nomath.Mat4 // Optional: for normal transformations
-	LightDots    []float64   // Optional: precomputed light factors
-	ModelMatrix  nomath.Mat4
+	Triangle    *assets.Triangle
+	Vertices    *TransformedMesh // The frame's transformed vertices of the triangle's Geometry
+	Transparent bool             // Blended over the opaque pass without writing depth
 }
 
 type Scene struct {
@@ -30,17 +29,25 @@ type Scene struct {
 	Lights         []*Light
 	Triangles      []*assets.Triangle
 	DrawnTriangles int32
+	Background     lookdev.ColorRGBA // Clear color used by offline renders
 
 	// caching matrices
 	cachedViewMatrix       nomath.Mat4
 	cachedProjectionMatrix nomath.Mat4
 	cachedViewProjMatrix   nomath.Mat4
+	transformed            map[*assets.Geometry]*TransformedMesh // Vertex stage output, reused each frame
+	transformedFrame       uint64                                // Marks the entries still in the scene, see pruneTransformed
+	lightDots              []float64                             // Backs the triangles' LightDotNormals, reused each frame
+	opaqueTasks            []RenderTask                          // The frame's tasks, kept to be reused next frame
+	transparentTasks       []transparentTask
+	sortedTasks            []RenderTask // Transparent tasks back to front
 
 	// Resolution scaling settings
-	ResolutionScale       float64 // Current scale (1.0 = full, 0.5 = half, etc.)
+	ResolutionScale       float64 // Current scale (1.0 = full, 0.5 = half, 2.0 = supersampled, etc.)
 	AutoResolution        bool    // Whether auto-scaling is enabled
 	LastFPS               int     // Track last FPS reading
 	MinResolutionScale    float64 // Minimum allowed resolution (e.g., 0.1 for 10%)
+	MaxResolutionScale    float64 // Maximum allowed resolution, supersampling above 1.0
 	LastScaleChange       float64 // Time since last resolution change (now float64)
 	FPSHistory            []int   // Store last few FPS readings for smoothing
 	FPSSum                int     // Sum of FPS history for averaging
@@ -59,12 +66,14 @@ func NewScene() *Scene {
 		Lights:       []*Light{default_light},
 		ViewAxes:     NewViewAxes(),
 		Grid:         NewGrid(),
+		Background:   lookdev.ColorRGBA{R: 0, G: 0, B: 0, A: 1.0},
 
 		// Resolution scaling defaults
 		ResolutionScale:       1.0,
 		AutoResolution:        false,
 		LastScaleChange:       0.0, // Initialize as float64
 		MinResolutionScale:    0.1, // Never go below 10%
+		MaxResolutionScale:    2.0, // Up to 2x2 supersampling when there is time to spare
 		FPSHistory:            make([]int, 0, 10),
 		TargetResolutionScale: 1.0,
 		ResolutionChangeSpeed: 0.25, // Adjust scale by up to 50% per second
@@ -88,101 +97,94 @@ func (s *Scene) UpdateScene() {
 	s.Renderer.PreComputeLightDirs(s)
 }
 
+// AddObject adds geom to the scene. Geometry whose triangles don't match its
+// Mesh is skipped with a warning rather than failing mid-frame.
 func (s *Scene) AddObject(geom *assets.Geometry) {
+	if err := geom.Validate(); err != nil {
+		log.Printf("Warning: not adding %s to the scene: %v", geom.Name, err)
+		return
+	}
 	geom.PrecomputeTextureBuffers()
 	s.Objects = append(s.Objects, geom)
 	s.Triangles = append(s.Triangles, geom.Triangles...)
 }
 
+// RenderScene renders a frame on a single goroutine
 func (s *Scene) RenderScene() {
-	s.DrawnTriangles = 0
-	s.UpdateScene()
-
-	viewDir := s.Camera.Transform.GetForward()
-	viewProjMatrix := s.cachedViewProjMatrix
-
-	// Precompute light dot normal per triangle
-	for _, triangle := range s.Triangles {
-		if !s.Camera.IsVisible(triangle.Parent.BoundingBox) ||
-			triangle.Normal().Dot(viewDir) > 0 || triangle.WorldNormal.Dot(viewDir) > 0 {
-			continue
-		}
-
-		modelMatrix := triangle.Parent.Transform.GetMatrix()
-		mvpMatrix := viewProjMatrix.Multiply(modelMatrix)
-		normalMatrix := modelMatrix.Inverse().Transpose()
-		// Transform triangle normal using normalMatrix
-		worldNormal := normalMatrix.TransformVec3(triangle.Normal()).Normalize()
-		triangle.WorldNormal = worldNormal
-
-		// Precompute light dot normal for each light
-		triangle.LightDotNormals = make([]float64, len(s.Lights))
-		for i, light := range s.Lights {
-			lightDir := light.GetDirection() // assuming normalized direction
-			triangle.LightDotNormals[i] = max(0, worldNormal.Dot(lightDir))
-		}
-
-		s.Renderer.RenderTriangle(&mvpMatrix, s.Camera, triangle, s.Lights, s)
-		s.DrawnTriangles++
-	}
+	s.render(1)
 }
 
+// RenderOnThread renders a frame with GOMAXPROCS workers. Screen tiles are
+// owned by one worker each, so the image is the same as RenderScene's.
 func (s *Scene) RenderOnThread() {
+	s.render(runtime.GOMAXPROCS(0))
+}
+
+func (s *Scene) render(workers int) {
+	s.Renderer.workers = max(1, workers)
 	s.UpdateScene()
-	atomic.StoreInt32(&s.DrawnTriangles, 0)
-	s.Renderer.PreComputeLightDirs(s)
+	s.RenderShadowMaps()
+	s.Renderer.prepareMSAA()
+	s.Renderer.prepareSSAO()
+
+	opaque, transparent := s.collectTasks()
+	s.Renderer.renderTasks(opaque, s.Camera, s.Lights, workers)
+	s.Renderer.resolveDepth()
+	s.Renderer.ApplySSAO(s.Camera)
+	s.renderTransparent(transparent, workers)
+	s.Renderer.Resolve()
+	s.Renderer.postProcess(s.Camera)
+	atomic.StoreInt32(&s.DrawnTriangles, int32(len(opaque)+len(transparent)))
+}
 
+// collectTasks culls the scene's triangles and returns the opaque ones along
+// with the transparent ones, which are drawn after SSAO
+func (s *Scene) collectTasks() ([]RenderTask, []transparentTask) {
 	// Safely get the view-projection matrix
 	s.matrixMutex.RLock()
 	viewProjMatrix := s.cachedViewProjMatrix
 	s.matrixMutex.RUnlock()
 	viewDir := s.Camera.Transform.GetForward()
 
-	var tasks []RenderTask
+	tasks := s.opaqueTasks[:0]
+	transparent := s.transparentTasks[:0]
+	s.pruneTransformed()
+	s.lightDots = s.lightDots[:0]
 
-	for _, triangle := range s.Triangles {
+	for _, obj := range s.Objects {
 		// Skip entire object if not in view
-		if !s.Camera.IsVisible(triangle.Parent.BoundingBox) {
+		if !s.Camera.IsVisible(obj.BoundingBox) {
 			continue
 		}
-
-		// Optional: finer culling per triangle
-		if triangle.Normal().Dot(viewDir) > 0 {
-			continue
-		}
-		modelMatrix := triangle.Parent.Transform.GetMatrix()
-		mvpMatrix := viewProjMatrix.Multiply(modelMatrix)
-
-		tasks = append(tasks, RenderTask{
-			Triangle: triangle,
-			MVP:      mvpMatrix,
-		})
-	}
-	// }
-
-	numWorkers := runtime.NumCPU()
-	var wg sync.WaitGroup
-	workChan := make(chan RenderTask, len(tasks))
-
-	worker := func() {
-		defer wg.Done()
-		var localCount int32
-
-		for task := range workChan {
-			s.Renderer.RenderTriangle(&task.MVP, s.Camera, task.Triangle, s.Lights, s)
-			localCount++
+		vertices := s.transformVertices(obj, viewProjMatrix)
+
+		// Precompute light dot normal per triangle
+		for _, triangle := range obj.Triangles {
+			if triangle.Normal().Dot(viewDir) > 0 || triangle.WorldNormal.Dot(viewDir) > 0 {
+				continue
+			}
+
+			// Transform triangle normal using normalMatrix
+			worldNormal := vertices.NormalMatrix.TransformVec3(triangle.Normal()).Normalize()
+			triangle.WorldNormal = worldNormal
+
+			// Precompute light dot normal for each light, attenuated at the centroid
+			centroid := vertices.centroid(triangle)
+			start := len(s.lightDots)
+			for i, light := range s.Lights {
+				lightDir, attenuation := light.illuminate(centroid, s.Renderer.lightDirection(i, s.Lights))
+				s.lightDots = append(s.lightDots, max(0, worldNormal.Dot(lightDir))*attenuation)
+			}
+			triangle.LightDotNormals = s.lightDots[start:len(s.lightDots):len(s.lightDots)]
+
+			task := RenderTask{Triangle: triangle, Vertices: vertices}
+			if triangle.Material.IsTransparent() {
+				transparent = append(transparent, s.newTransparentTask(task, centroid))
+				continue
+			}
+			tasks = append(tasks, task)
 		}
-		atomic.AddInt32(&s.DrawnTriangles, localCount)
-	}
-
-	wg.Add(numWorkers)
-	for i := 0; i < numWorkers; i++ {
-		go worker()
-	}
-
-	for _, task := range tasks {
-		workChan <- task
 	}
-	close(workChan)
-	wg.Wait()
+	s.opaqueTasks, s.transparentTasks = tasks, transparent
+	return tasks, transparent
 }
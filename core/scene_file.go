@@ -0,0 +1,330 @@
+package core
+
+import (
+	"GopherEngine/assets"
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SceneFile describes a scene for offline rendering. Paths are resolved
+// relative to the directory of the scene file.
+//
+//	{
+//	  "width": 1280, "height": 720, "frames": 24,
+//	  "gltf": "models/street.glb",
+//	  "objects": [{"obj": "objs/house.obj", "texture": "textures/ground_grid.png", "position": [0, 0, -20]}],
+//	  "camera": {"focal_length": 75, "near": 0.1, "far": 10000},
+//	  "camera_path": [{"position": [0, 10, 10]}, {"position": [10, 10, 10], "rotation": [0, 0.5, 0]}],
+//	  "lights": [{"type": "point", "position": [0, 20, 0], "color": [255, 200, 150], "intensity": 2}]
+//	}
+//
+// When lights are listed they replace the scene's default directional light,
+// or the lights of the glTF file. The camera settings likewise override the
+// glTF file's camera.
+type SceneFile struct {
+	Width      int                  `json:"width"`
+	Height     int                  `json:"height"`
+	Frames     int                  `json:"frames"`
+	GLTF       string               `json:"gltf"` // glTF or GLB file with meshes, cameras and lights
+	Background [3]uint8             `json:"background"`
+	Objects    []SceneFileObject    `json:"objects"`
+	Camera     SceneFileCamera      `json:"camera"`
+	CameraPath []SceneFileTransform `json:"camera_path"`
+	Lights     []SceneFileLight     `json:"lights"`
+}
+
+type SceneFileObject struct {
+	OBJ                 string      `json:"obj"`          // OBJ, STL or PLY file
+	SplitGroups         bool        `json:"split_groups"` // One object per OBJ group, see assets.LoadOBJGroups
+	Texture             string      `json:"texture"`
+	SpecularTexture     string      `json:"specular_texture"`
+	NormalTexture       string      `json:"normal_texture"` // Tangent-space normal map
+	TransparencyTexture string      `json:"transparency_texture"`
+	Position            *[3]float64 `json:"position"`
+	Rotation            *[3]float64 `json:"rotation"`
+	Scale               *[3]float64 `json:"scale"`
+	DiffuseColor        *[3]uint8   `json:"diffuse_color"`
+	SpecularColor       *[3]uint8   `json:"specular_color"`
+	Shininess           *float64    `json:"shininess"`
+	Transparency        *float64    `json:"transparency"` // 0 opaque to 1 invisible
+	AlphaCutoff         *float64    `json:"alpha_cutoff"` // Cut out texels below this alpha
+	CastShadows         *bool       `json:"cast_shadows"`
+	ReceiveShadows      *bool       `json:"receive_shadows"`
+}
+
+type SceneFileCamera struct {
+	FocalLength int     `json:"focal_length"`
+	NearPlane   float64 `json:"near"`
+	FarPlane    float64 `json:"far"`
+	SceneFileTransform
+}
+
+type SceneFileLight struct {
+	Type        string      `json:"type"` // "directional", "point" or "spot"
+	Position    *[3]float64 `json:"position"`
+	Rotation    *[3]float64 `json:"rotation"`   // Aims spot lights
+	InnerCone   *float64    `json:"inner_cone"` // Spot half-angles in radians
+	OuterCone   *float64    `json:"outer_cone"`
+	Direction   *[3]float64 `json:"direction"` // Toward the light, for directional lights
+	Color       *[3]uint8   `json:"color"`
+	Intensity   *float64    `json:"intensity"`
+	Attenuation *[3]float64 `json:"attenuation"` // Constant, linear, quadratic
+
+	CastShadows   *bool    `json:"cast_shadows"`
+	ShadowMapSize *int     `json:"shadow_map_size"`
+	ShadowBias    *float64 `json:"shadow_bias"`
+	ShadowPCF     *int     `json:"shadow_pcf"` // PCF kernel radius in texels
+}
+
+type SceneFileTransform struct {
+	Position *[3]float64 `json:"position"`
+	Rotation *[3]float64 `json:"rotation"`
+}
+
+// LoadSceneFile reads a JSON scene description, loads every referenced asset
+// and returns the scene along with the camera path it declares (if any).
+func LoadSceneFile(filename string) (*Scene, *CameraPath, *SceneFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var desc SceneFile
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid scene file %s: %v", filename, err)
+	}
+
+	scene, path, err := desc.Build(filepath.Dir(filename))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return scene, path, &desc, nil
+}
+
+// Build creates a scene from the description, resolving asset paths against baseDir
+func (desc *SceneFile) Build(baseDir string) (*Scene, *CameraPath, error) {
+	scene := NewScene()
+	scene.Background = lookdev.ColorRGBA{R: desc.Background[0], G: desc.Background[1], B: desc.Background[2], A: 1.0}
+
+	if desc.GLTF != "" {
+		gltf, err := assets.LoadGLTF(resolvePath(baseDir, desc.GLTF))
+		if err != nil {
+			return nil, nil, err
+		}
+		scene.AddGLTF(gltf)
+	}
+
+	for i, obj := range desc.Objects {
+		if obj.OBJ == "" {
+			return nil, nil, fmt.Errorf("object %d: missing obj path", i)
+		}
+		var geoms []*assets.Geometry
+		if obj.SplitGroups {
+			parts, err := assets.LoadOBJGroups(resolvePath(baseDir, obj.OBJ))
+			if err != nil {
+				return nil, nil, fmt.Errorf("object %d: %v", i, err)
+			}
+			geoms = parts
+		} else {
+			geom, err := assets.LoadModel(resolvePath(baseDir, obj.OBJ))
+			if err != nil {
+				return nil, nil, fmt.Errorf("object %d: %v", i, err)
+			}
+			geoms = []*assets.Geometry{geom}
+		}
+		for _, geom := range geoms {
+			if err := obj.apply(geom, baseDir); err != nil {
+				return nil, nil, fmt.Errorf("object %d: %v", i, err)
+			}
+			scene.AddObject(geom)
+		}
+	}
+
+	if desc.Camera.FocalLength > 0 {
+		scene.Camera.FocalLength = desc.Camera.FocalLength
+	}
+	if desc.Camera.NearPlane > 0 {
+		scene.Camera.NearPlane = desc.Camera.NearPlane
+	}
+	if desc.Camera.FarPlane > 0 {
+		scene.Camera.FarPlane = desc.Camera.FarPlane
+	}
+	desc.Camera.SceneFileTransform.apply(scene.Camera.Transform)
+
+	if len(desc.Lights) > 0 {
+		scene.Lights = scene.Lights[:0]
+		for i, l := range desc.Lights {
+			light, err := l.build()
+			if err != nil {
+				return nil, nil, fmt.Errorf("light %d: %v", i, err)
+			}
+			scene.Lights = append(scene.Lights, light)
+		}
+		scene.DefaultLight = scene.Lights[0]
+	}
+
+	var path *CameraPath
+	if len(desc.CameraPath) > 0 {
+		path = &CameraPath{}
+		for _, key := range desc.CameraPath {
+			// Keyframes inherit anything they don't set from the camera
+			t := nomath.NewTransform()
+			t.Position = scene.Camera.Transform.Position
+			t.Rotation = scene.Camera.Transform.Rotation
+			key.apply(t)
+			path.Keyframes = append(path.Keyframes, CameraKeyframe{Position: t.Position, Rotation: t.Rotation})
+		}
+	}
+
+	return scene, path, nil
+}
+
+func (o *SceneFileObject) apply(geom *assets.Geometry, baseDir string) error {
+	if o.Texture != "" {
+		tex, err := lookdev.LoadTexture(resolvePath(baseDir, o.Texture))
+		if err != nil {
+			return fmt.Errorf("failed to load texture: %v", err)
+		}
+		geom.Material.DiffuseTexture = tex
+	}
+	if o.SpecularTexture != "" {
+		tex, err := lookdev.LoadTexture(resolvePath(baseDir, o.SpecularTexture))
+		if err != nil {
+			return fmt.Errorf("failed to load specular texture: %v", err)
+		}
+		geom.Material.SpecularTexture = tex
+	}
+	if o.NormalTexture != "" {
+		tex, err := lookdev.LoadTexture(resolvePath(baseDir, o.NormalTexture))
+		if err != nil {
+			return fmt.Errorf("failed to load normal texture: %v", err)
+		}
+		geom.Material.NormalTexture = tex
+	}
+	if o.TransparencyTexture != "" {
+		tex, err := lookdev.LoadTexture(resolvePath(baseDir, o.TransparencyTexture))
+		if err != nil {
+			return fmt.Errorf("failed to load transparency texture: %v", err)
+		}
+		geom.Material.TransparencyTexture = tex
+	}
+	if o.DiffuseColor != nil {
+		geom.Material.DiffuseColor = *lookdev.NewColorRGB(o.DiffuseColor[0], o.DiffuseColor[1], o.DiffuseColor[2])
+	}
+	if o.SpecularColor != nil {
+		geom.Material.SpecularColor = *lookdev.NewColorRGB(o.SpecularColor[0], o.SpecularColor[1], o.SpecularColor[2])
+	}
+	if o.Shininess != nil {
+		geom.Material.Shininess = *o.Shininess
+	}
+	if o.Transparency != nil {
+		geom.Material.Transparency = *o.Transparency
+	}
+	if o.AlphaCutoff != nil {
+		geom.Material.AlphaCutoff = *o.AlphaCutoff
+	}
+	if o.CastShadows != nil {
+		geom.CastShadows = *o.CastShadows
+	}
+	if o.ReceiveShadows != nil {
+		geom.ReceiveShadows = *o.ReceiveShadows
+	}
+
+	// Split parts already sit at their own centre, which moves with the object
+	placement := nomath.NewTransform()
+	if o.Position != nil {
+		placement.SetPosition(toVec3(*o.Position))
+	}
+	if o.Rotation != nil {
+		placement.SetRotation(toVec3(*o.Rotation))
+	}
+	if o.Scale != nil {
+		placement.SetScale(toVec3(*o.Scale))
+	}
+	centre := placement.GetMatrix().MultiplyVec4(geom.Transform.Position.ToVec4(1)).ToVec3()
+	geom.Transform.SetPosition(centre)
+	geom.Transform.SetRotation(placement.Rotation)
+	geom.Transform.SetScale(placement.Scale)
+	return nil
+}
+
+func (l *SceneFileLight) build() (*Light, error) {
+	var light *Light
+	switch l.Type {
+	case "", "directional":
+		light = NewDirectionalLight()
+	case "point":
+		light = NewPointLight()
+	case "spot":
+		light = NewSpotLight()
+	default:
+		return nil, fmt.Errorf("unknown light type %q", l.Type)
+	}
+
+	if l.Position != nil {
+		light.Transform.SetPosition(toVec3(*l.Position))
+		light.Transform.UpdateModelMatrix()
+		if light.Type == LightTypeDirectional {
+			light.Direction = light.Transform.Position.Normalize()
+		}
+	}
+	if l.Rotation != nil {
+		light.Transform.SetRotation(toVec3(*l.Rotation))
+		light.Transform.UpdateModelMatrix()
+	}
+	if l.InnerCone != nil {
+		light.InnerConeAngle = *l.InnerCone
+	}
+	if l.OuterCone != nil {
+		light.OuterConeAngle = *l.OuterCone
+	}
+	if l.Direction != nil {
+		light.Direction = toVec3(*l.Direction).Normalize()
+	}
+	if l.Color != nil {
+		light.Color = lookdev.NewColorRGB(l.Color[0], l.Color[1], l.Color[2])
+	}
+	if l.Intensity != nil {
+		light.Intensity = *l.Intensity
+	}
+	if l.Attenuation != nil {
+		light.Attenuation = Attenuation{Constant: l.Attenuation[0], Linear: l.Attenuation[1], Quadratic: l.Attenuation[2]}
+	}
+	if l.CastShadows != nil {
+		light.CastShadows = *l.CastShadows
+	}
+	if l.ShadowMapSize != nil {
+		light.ShadowMapSize = *l.ShadowMapSize
+	}
+	if l.ShadowBias != nil {
+		light.ShadowBias = *l.ShadowBias
+	}
+	if l.ShadowPCF != nil {
+		light.ShadowPCFRadius = *l.ShadowPCF
+	}
+	return light, nil
+}
+
+func (t *SceneFileTransform) apply(transform *nomath.Transform) {
+	if t.Position != nil {
+		transform.SetPosition(toVec3(*t.Position))
+	}
+	if t.Rotation != nil {
+		transform.SetRotation(toVec3(*t.Rotation))
+	}
+}
+
+func toVec3(v [3]float64) nomath.Vec3 {
+	return nomath.Vec3{X: v[0], Y: v[1], Z: v[2]}
+}
+
+func resolvePath(baseDir, path string) string {
+	if filepath.IsAbs(path) || baseDir == "" {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"GopherEngine/assets"
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"math"
+)
+
+// lightTerms is the colored light reaching a surface point, split into the
+// part scaled by the diffuse color and the part scaled by the specular color.
+type lightTerms struct {
+	Diffuse  nomath.Vec3
+	Specular nomath.Vec3
+}
+
+func (t lightTerms) add(other lightTerms) lightTerms {
+	return lightTerms{Diffuse: t.Diffuse.Add(other.Diffuse), Specular: t.Specular.Add(other.Specular)}
+}
+
+func (t lightTerms) scale(factor float64) lightTerms {
+	return lightTerms{Diffuse: t.Diffuse.Multiply(factor), Specular: t.Specular.Multiply(factor)}
+}
+
+// lightTermsAt evaluates every light for a surface point with the given world
+// position and normal. viewDir points from the surface toward the eye.
+func (r *Renderer3D) lightTermsAt(position, normal, viewDir nomath.Vec3, shininess float64, lights []*Light, receiveShadows bool) lightTerms {
+	var terms lightTerms
+	for i, light := range lights {
+		terms = terms.add(r.evaluateLight(light, r.lightDirection(i, lights), position, normal, viewDir, shininess, receiveShadows))
+	}
+	return terms
+}
+
+// evaluateLight returns the contribution of a single light. direction is the
+// light's cached direction from PreComputeLightDirs.
+func (r *Renderer3D) evaluateLight(light *Light, direction, position, normal, viewDir nomath.Vec3, shininess float64, receiveShadows bool) lightTerms {
+	lightDir, attenuation := light.illuminate(position, direction)
+	diffuseFactor := math.Max(0, normal.Dot(lightDir)) * attenuation
+	if diffuseFactor == 0 {
+		return lightTerms{}
+	}
+	if receiveShadows && r.hasShadowMap(light) {
+		visibility := r.shadowFactor(light, position)
+		if visibility == 0 {
+			return lightTerms{}
+		}
+		attenuation *= visibility
+		diffuseFactor *= visibility
+	}
+
+	radiance := light.Radiance()
+
+	// Specular (Blinn-Phong)
+	halfDir := lightDir.Add(viewDir).Normalize()
+	specFactor := math.Pow(math.Max(0, normal.Dot(halfDir)), shininess) * attenuation
+
+	return lightTerms{
+		Diffuse:  radiance.Multiply(diffuseFactor),
+		Specular: radiance.Multiply(specFactor),
+	}
+}
+
+// anyShadowMaps reports whether any light will test against a shadow map
+func (r *Renderer3D) anyShadowMaps(lights []*Light) bool {
+	for _, light := range lights {
+		if r.hasShadowMap(light) {
+			return true
+		}
+	}
+	return false
+}
+
+// shade combines surface colors with the light reaching them, in linear
+// light and without clamping
+func (r *Renderer3D) shade(diffuse, specular *lookdev.ColorRGBA, terms lightTerms) lookdev.ColorHDR {
+	d, s := diffuse.Linear(), specular.Linear()
+	ambient := r.ambienceFactor
+	return lookdev.ColorHDR{
+		R: d.R*float32(ambient+terms.Diffuse.X) + s.R*float32(terms.Specular.X),
+		G: d.G*float32(ambient+terms.Diffuse.Y) + s.G*float32(terms.Specular.Y),
+		B: d.B*float32(ambient+terms.Diffuse.Z) + s.B*float32(terms.Specular.Z),
+	}
+}
+
+func (r *Renderer3D) calculateLightingWithPrecomputed(diffuse, specular *lookdev.ColorRGBA, tri *assets.Triangle, position nomath.Vec3, lights []*Light, receiveShadows bool) lookdev.ColorHDR {
+	var terms lightTerms
+
+	// Apply precomputed lighting factors, tinted by each light's color
+	for i, dot := range tri.LightDotNormals {
+		if i >= len(lights) {
+			break
+		}
+		if dot > 0 && receiveShadows && r.hasShadowMap(lights[i]) {
+			dot *= r.shadowFactor(lights[i], position)
+		}
+		terms.Diffuse = terms.Diffuse.Add(lights[i].Radiance().Multiply(dot))
+	}
+
+	result := r.shade(diffuse, specular, terms)
+
+	// Apply specular if available
+	if specular != nil {
+		s := specular.Linear()
+		result.R += s.R
+		result.G += s.G
+		result.B += s.B
+	}
+
+	return result
+}
+
+func (r *Renderer3D) calculateLighting(diffuse, specular *lookdev.ColorRGBA, tri *assets.Triangle, position, normal, viewDir nomath.Vec3, lights []*Light, receiveShadows bool) lookdev.ColorHDR {
+	return r.shade(diffuse, specular, r.lightTermsAt(position, normal, viewDir, tri.Material.Shininess, lights, receiveShadows))
+}
+
+// perspectiveWeights converts screen-space barycentrics of a (possibly clipped)
+// triangle into perspective-correct weights on the source triangle's vertices.
+func perspectiveWeights(verts [3]clipVertex, invW [3]float64, u, v, w float64) nomath.Vec3 {
+	pu := u * invW[0]
+	pv := v * invW[1]
+	pw := w * invW[2]
+	sum := pu + pv + pw
+	if sum == 0 {
+		return verts[0].Weights
+	}
+	return verts[0].Weights.Multiply(pu).
+		Add(verts[1].Weights.Multiply(pv)).
+		Add(verts[2].Weights.Multiply(pw)).
+		Multiply(1.0 / sum)
+}
+
+func interpolateVec3(values [3]nomath.Vec3, weights nomath.Vec3) nomath.Vec3 {
+	return values[0].Multiply(weights.X).
+		Add(values[1].Multiply(weights.Y)).
+		Add(values[2].Multiply(weights.Z))
+}
+
+func interpolateLightTerms(values [3]lightTerms, weights nomath.Vec3) lightTerms {
+	return lightTerms{
+		Diffuse:  interpolateVec3([3]nomath.Vec3{values[0].Diffuse, values[1].Diffuse, values[2].Diffuse}, weights),
+		Specular: interpolateVec3([3]nomath.Vec3{values[0].Specular, values[1].Specular, values[2].Specular}, weights),
+	}
+}
+
+// mappedNormal perturbs the interpolated normal with the tangent-space normal
+// map of the triangle's material
+func (setup *triangleSetup) mappedNormal(weights nomath.Vec3) nomath.Vec3 {
+	tri := setup.tri
+	normal := interpolateVec3(setup.worldNormal, weights).Normalize()
+	tangent := interpolateVec3(setup.worldTangent, weights)
+	bitangent := interpolateVec3(setup.worldBitangent, weights).Normalize()
+
+	// Interpolation skews the frame slightly, so straighten the tangent again
+	tangent = tangent.Subtract(normal.Multiply(normal.Dot(tangent))).Normalize()
+
+	uv := tri.InterpolatedUV(weights.X, weights.Y, weights.Z)
+	texel := tri.Material.NormalTexture.Sample(uv.U, uv.V)
+	x := float64(texel.R)/127.5 - 1
+	y := float64(texel.G)/127.5 - 1
+	z := float64(texel.B)/127.5 - 1
+
+	return tangent.Multiply(x).Add(bitangent.Multiply(y)).Add(normal.Multiply(z)).Normalize()
+}
+
+// sampleSurface samples the material textures of tri at the given barycentric weights
+func sampleSurface(tri *assets.Triangle, weights nomath.Vec3) (*lookdev.ColorRGBA, *lookdev.ColorRGBA) {
+	uv := tri.InterpolatedUV(weights.X, weights.Y, weights.Z)
+	diffuse := tri.Material.DiffuseColor
+	specular := tri.Material.SpecularColor
+
+	if tri.Material.DiffuseTexture != nil {
+		diffuse = tri.Material.DiffuseTexture.Sample(uv.U, uv.V)
+		if tri.Material.DiffuseColor.A < 1.0 {
+			diffuse.A *= tri.Material.DiffuseColor.A
+		}
+	}
+	if tri.Material.SpecularTexture != nil {
+		specular = tri.Material.SpecularTexture.Sample(uv.U, uv.V)
+	}
+	return &diffuse, &specular
+}
+
+// tintByVertexColor multiplies a diffuse color by the vertex colors of tri
+// interpolated at the given barycentric weights
+func tintByVertexColor(diffuse *lookdev.ColorRGBA, tri *assets.Triangle, weights nomath.Vec3) *lookdev.ColorRGBA {
+	vertex := tri.InterpolatedColor(weights.X, weights.Y, weights.Z)
+	result := *diffuse
+	result.R = uint8(uint16(diffuse.R) * uint16(vertex.R) / 255)
+	result.G = uint8(uint16(diffuse.G) * uint16(vertex.G) / 255)
+	result.B = uint8(uint16(diffuse.B) * uint16(vertex.B) / 255)
+	return &result
+}
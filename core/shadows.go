@@ -0,0 +1,223 @@
+package core
+
+import (
+	"GopherEngine/assets"
+	"GopherEngine/nomath"
+	"math"
+)
+
+// ShadowMap is a depth image of the scene as seen from a light
+type ShadowMap struct {
+	Size     int
+	Depth    []float32   // Size*Size depths in [0, 1], row-major
+	ViewProj nomath.Mat4 // World space to the light's clip space
+}
+
+func NewShadowMap(size int) *ShadowMap {
+	size = max(1, size)
+	return &ShadowMap{
+		Size:  size,
+		Depth: make([]float32, size*size),
+	}
+}
+
+func (sm *ShadowMap) clear() {
+	for i := range sm.Depth {
+		sm.Depth[i] = math.MaxFloat32
+	}
+}
+
+// hasShadowMap reports whether the light's shadow map should be used this frame
+func (r *Renderer3D) hasShadowMap(light *Light) bool {
+	return r.ShadowsEnabled && light.CastShadows && light.shadowMap != nil
+}
+
+// RenderShadowMaps renders a depth map for every shadow-casting light.
+// Directional lights use an orthographic projection fitted around the shadow
+// casters, spot lights a perspective projection covering their outer cone.
+func (s *Scene) RenderShadowMaps() {
+	if !s.Renderer.ShadowsEnabled {
+		return
+	}
+
+	// Grown in place, as a Union per caster would allocate a box for each
+	var casters nomath.BoundingBox
+	var bounds *nomath.BoundingBox
+	for _, obj := range s.Objects {
+		if !obj.CastShadows || obj.BoundingBox == nil {
+			continue
+		}
+		if bounds == nil {
+			casters = *obj.BoundingBox
+			bounds = &casters
+		} else {
+			casters.Min = nomath.Min(casters.Min, obj.BoundingBox.Min)
+			casters.Max = nomath.Max(casters.Max, obj.BoundingBox.Max)
+		}
+	}
+
+	for i, light := range s.Lights {
+		// A directional light left without a direction has nowhere to look from
+		direction := s.Renderer.lightDirection(i, s.Lights)
+		if !light.CastShadows || light.Type == LightTypePoint || bounds == nil || direction == (nomath.Vec3{}) {
+			light.shadowMap = nil
+			continue
+		}
+		if light.shadowMap == nil || light.shadowMap.Size != light.ShadowMapSize {
+			light.shadowMap = NewShadowMap(light.ShadowMapSize)
+		}
+
+		sm := light.shadowMap
+		sm.ViewProj = lightViewProjection(light, direction, bounds)
+		sm.clear()
+		s.Renderer.renderShadowCasters(sm, s.Objects)
+	}
+}
+
+// lightViewProjection builds the matrix that maps world space into the light's view
+func lightViewProjection(light *Light, direction nomath.Vec3, bounds *nomath.BoundingBox) nomath.Mat4 {
+	center := bounds.Center()
+	radius := math.Max(bounds.Size().Length()*0.5, 0.001)
+
+	// Any up vector works as long as it isn't parallel to the view direction
+	up := nomath.Vec3{Y: 1}
+	if math.Abs(direction.Dot(up)) > 0.99 {
+		up = nomath.Vec3{Z: 1}
+	}
+
+	if light.Type == LightTypeDirectional {
+		eye := center.Add(direction.Multiply(radius * 2))
+		view := nomath.LookAtMatrix(eye, center, up)
+		projection := nomath.OrthographicMatrix(-radius, radius, -radius, radius, radius*0.5, radius*3.5)
+		return projection.Multiply(view)
+	}
+
+	eye := light.Transform.GetWorldPosition()
+	far := 0.0
+	for _, corner := range bounds.Corners() {
+		far = math.Max(far, corner.Subtract(eye).Length())
+	}
+	far = math.Max(far*1.05, 1.0)
+	near := math.Max(far*0.001, 0.05)
+
+	view := nomath.LookAtMatrix(eye, eye.Subtract(direction), up)
+	fov := math.Min(2*light.OuterConeAngle+0.05, math.Pi*0.95)
+	projection := nomath.PerspectiveMatrix(fov, 1.0, near, far)
+	return projection.Multiply(view)
+}
+
+// renderShadowCasters rasterizes the depth of every shadow-casting triangle into sm
+func (r *Renderer3D) renderShadowCasters(sm *ShadowMap, objects []*assets.Geometry) {
+	var clip []nomath.Vec4
+	for _, obj := range objects {
+		if !obj.CastShadows || obj.Mesh == nil {
+			continue
+		}
+		// Transform each shared vertex once rather than once per triangle
+		mvp := sm.ViewProj.Multiply(obj.Transform.GetMatrix())
+		clip = clip[:0]
+		for _, p := range obj.Mesh.Positions {
+			clip = append(clip, mvp.MultiplyVec4(p.ToVec4(1.0)))
+		}
+		for _, tri := range obj.Triangles {
+			v0, v1, v2 := clip[tri.Indices[0]], clip[tri.Indices[1]], clip[tri.Indices[2]]
+
+			// Spot projections can put vertices behind the light; the map only
+			// needs casters in front of it, so those triangles are dropped
+			if v0.W <= 0 || v1.W <= 0 || v2.W <= 0 {
+				continue
+			}
+
+			// Alpha-tested texels let light through, so the map needs the UVs
+			var cutout *assets.Triangle
+			if tri.Material.IsAlphaTested() {
+				cutout = tri
+			}
+			invW := [3]float64{1 / v0.W, 1 / v1.W, 1 / v2.W}
+			sm.rasterizeDepth(v0.ToVec3(), v1.ToVec3(), v2.ToVec3(), invW, cutout)
+		}
+	}
+}
+
+// rasterizeDepth writes the nearest depth of an NDC triangle into the map.
+// When cutout is set, texels below its material's alpha cutoff are skipped.
+func (sm *ShadowMap) rasterizeDepth(a, b, c nomath.Vec3, invW [3]float64, cutout *assets.Triangle) {
+	size := float64(sm.Size)
+	toMap := func(p nomath.Vec3) (float64, float64, float64) {
+		return (p.X + 1) * 0.5 * size, (1 - (p.Y+1)*0.5) * size, (p.Z + 1) * 0.5
+	}
+	x0, y0, z0 := toMap(a)
+	x1, y1, z1 := toMap(b)
+	x2, y2, z2 := toMap(c)
+
+	area := (x1-x0)*(y2-y0) - (x2-x0)*(y1-y0)
+	if math.Abs(area) < 1e-12 {
+		return
+	}
+	invArea := 1.0 / area
+
+	minX := max(0, int(math.Floor(math.Min(x0, math.Min(x1, x2)))))
+	maxX := min(sm.Size-1, int(math.Ceil(math.Max(x0, math.Max(x1, x2)))))
+	minY := max(0, int(math.Floor(math.Min(y0, math.Min(y1, y2)))))
+	maxY := min(sm.Size-1, int(math.Ceil(math.Max(y0, math.Max(y1, y2)))))
+
+	for y := minY; y <= maxY; y++ {
+		py := float64(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float64(x) + 0.5
+			w0 := ((x1-px)*(y2-py) - (x2-px)*(y1-py)) * invArea
+			w1 := ((x2-px)*(y0-py) - (x0-px)*(y2-py)) * invArea
+			w2 := 1 - w0 - w1
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+			depth := float32(w0*z0 + w1*z1 + w2*z2)
+			idx := y*sm.Size + x
+			if depth < 0 || depth >= sm.Depth[idx] {
+				continue
+			}
+			if cutout != nil {
+				p0, p1, p2 := w0*invW[0], w1*invW[1], w2*invW[2]
+				sum := p0 + p1 + p2
+				uv := cutout.InterpolatedUV(p0/sum, p1/sum, p2/sum)
+				if cutout.Material.IsCutout(uv.U, uv.V) {
+					continue
+				}
+			}
+			sm.Depth[idx] = depth
+		}
+	}
+}
+
+// shadowFactor returns how lit a world position is for the light, from 0
+// (fully shadowed) to 1, filtering the comparison over a PCF kernel.
+func (r *Renderer3D) shadowFactor(light *Light, position nomath.Vec3) float64 {
+	sm := light.shadowMap
+	clip := sm.ViewProj.MultiplyVec4(position.ToVec4(1.0))
+	if clip.W <= 0 {
+		return 1.0
+	}
+	ndc := clip.ToVec3()
+	if ndc.X < -1 || ndc.X > 1 || ndc.Y < -1 || ndc.Y > 1 || ndc.Z > 1 {
+		return 1.0
+	}
+
+	size := float64(sm.Size)
+	cx := int((ndc.X + 1) * 0.5 * size)
+	cy := int((1 - (ndc.Y+1)*0.5) * size)
+	depth := float32((ndc.Z+1)*0.5 - light.ShadowBias)
+
+	radius := max(0, light.ShadowPCFRadius)
+	lit, total := 0, 0
+	for dy := -radius; dy <= radius; dy++ {
+		y := min(sm.Size-1, max(0, cy+dy))
+		for dx := -radius; dx <= radius; dx++ {
+			x := min(sm.Size-1, max(0, cx+dx))
+			if depth <= sm.Depth[y*sm.Size+x] {
+				lit++
+			}
+			total++
+		}
+	}
+	return float64(lit) / float64(total)
+}
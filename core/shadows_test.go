@@ -0,0 +1,141 @@
+package core
+
+import (
+	"GopherEngine/assets"
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadTestOBJ loads geometry from OBJ source written to a temporary file
+func loadTestOBJ(t *testing.T, source string) *assets.Geometry {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.obj")
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	geom, err := assets.LoadOBJ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return geom
+}
+
+// testShadowMap is a 4x4 map with an occluder at depth 0.25 over its left
+// half, seen straight on so NDC is world space
+func testShadowMap() *ShadowMap {
+	sm := NewShadowMap(4)
+	sm.clear()
+	sm.ViewProj = nomath.IdentityMatrix()
+	for y := 0; y < 4; y++ {
+		sm.Depth[y*4] = 0.25
+		sm.Depth[y*4+1] = 0.25
+	}
+	return sm
+}
+
+func TestShadowFactor(t *testing.T) {
+	r := NewRenderer3D()
+	light := NewDirectionalLight()
+	light.shadowMap = testShadowMap()
+
+	// Texel columns are 0.5 wide in NDC: column 1 spans x in [-0.5, 0), column 2 [0, 0.5)
+	tests := []struct {
+		name      string
+		pcfRadius int
+		bias      float64
+		position  nomath.Vec3
+		want      float64
+	}{
+		{"behind the occluder", 0, 0, nomath.Vec3{X: -0.75}, 0},
+		{"beside the occluder", 0, 0, nomath.Vec3{X: 0.75}, 1},
+		{"in front of the occluder", 0, 0, nomath.Vec3{X: -0.75, Z: -0.8}, 1},
+		{"within the bias", 0, 0.1, nomath.Vec3{X: -0.75, Z: -0.4}, 1},
+		{"outside the map", 0, 0, nomath.Vec3{X: -1.5}, 1},
+		{"beyond the far plane", 0, 0, nomath.Vec3{X: -0.75, Z: 1.5}, 1},
+		{"PCF inside the shadow", 1, 0, nomath.Vec3{X: -0.25}, 1.0 / 3},
+		{"PCF outside the shadow", 1, 0, nomath.Vec3{X: 0.25}, 2.0 / 3},
+		{"PCF clamped at the edge", 1, 0, nomath.Vec3{X: -0.9}, 0},
+		{"PCF far from the edge", 1, 0, nomath.Vec3{X: 0.9}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			light.ShadowPCFRadius = tt.pcfRadius
+			light.ShadowBias = tt.bias
+			if got := r.shadowFactor(light, tt.position); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderShadowMaps(t *testing.T) {
+	scene := NewScene()
+	// A 2x2 roof at y = 1 over the origin
+	scene.AddObject(loadTestOBJ(t, `v -1 1 -1
+v 1 1 -1
+v 1 1 1
+v -1 1 1
+f 1 2 3 4
+`))
+
+	sun := NewDirectionalLight()
+	sun.CastShadows = true
+	sun.ShadowMapSize = 64
+	sun.ShadowPCFRadius = 0
+	sun.Direction = nomath.Vec3{Y: 1}
+	unaimed := NewDirectionalLight()
+	unaimed.CastShadows = true
+	scene.Lights = []*Light{sun, unaimed}
+
+	scene.UpdateScene()
+	scene.RenderShadowMaps()
+
+	if !scene.Renderer.hasShadowMap(sun) {
+		t.Fatal("the sun has no shadow map")
+	}
+	if got := scene.Renderer.shadowFactor(sun, nomath.Vec3{X: 0.2, Z: -0.3}); got != 0 {
+		t.Errorf("point under the roof is lit by %v", got)
+	}
+	if got := scene.Renderer.shadowFactor(sun, nomath.Vec3{X: 0.2, Y: 1.5, Z: -0.3}); got != 1 {
+		t.Errorf("point above the roof is lit by %v, want 1", got)
+	}
+	for _, v := range sun.shadowMap.ViewProj {
+		if math.IsNaN(v) {
+			t.Fatalf("shadow matrix %v", sun.shadowMap.ViewProj)
+		}
+	}
+
+	// Without a direction there is nowhere to render the map from
+	if scene.Renderer.hasShadowMap(unaimed) {
+		t.Error("a directional light without a direction got a shadow map")
+	}
+}
+
+func TestShadowMapCutout(t *testing.T) {
+	scene := NewScene()
+	leaf := testQuad(t, -1, -1, 1, 1, 0, lookdev.NewMaterial("leaf"))
+	leaf.Material.DiffuseTexture = cutoutTexture()
+	leaf.Material.AlphaCutoff = 0.5
+	scene.AddObject(leaf)
+
+	// Shine along -Z through the quad
+	sun := NewDirectionalLight()
+	sun.CastShadows = true
+	sun.ShadowMapSize = 64
+	sun.ShadowPCFRadius = 0
+	sun.Direction = nomath.Vec3{Z: 1}
+	scene.Lights = []*Light{sun}
+	scene.UpdateScene()
+	scene.RenderShadowMaps()
+
+	if got := scene.Renderer.shadowFactor(sun, nomath.Vec3{X: -0.5, Z: -0.5}); got != 1 {
+		t.Errorf("behind the cut out half is lit by %v, want 1", got)
+	}
+	if got := scene.Renderer.shadowFactor(sun, nomath.Vec3{X: 0.5, Z: -0.5}); got != 0 {
+		t.Errorf("behind the opaque half is lit by %v, want 0", got)
+	}
+}
@@ -0,0 +1,238 @@
+package core
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"math"
+	"math/rand"
+)
+
+const ssaoNoiseSize = 4 // The noise tile, and therefore the blur, is 4x4 pixels
+
+// prepareSSAO makes sure the SSAO buffers, kernel and noise exist for the
+// current resolution. Everything is allocated lazily so a renderer that never
+// enables SSAO pays nothing for it.
+func (r *Renderer3D) prepareSSAO() {
+	if !r.SSAOEnabled {
+		return
+	}
+	if pixels := r.GetWidth() * r.GetHeight(); len(r.SSAOBuffer) != pixels || len(r.ssaoNormals) != pixels {
+		r.allocateSSAOBuffers(pixels)
+	}
+	if len(r.SSAOKernel) != r.SSAOSamples {
+		r.SSAOKernel = generateSSAOKernel(r.SSAOSamples)
+	}
+	if r.SSAONoiseTexture == nil {
+		r.SSAONoiseTexture = generateSSAONoise(ssaoNoiseSize)
+	}
+}
+
+func (r *Renderer3D) allocateSSAOBuffers(pixels int) {
+	r.SSAOBuffer = make([]float32, pixels)
+	r.ssaoBlurred = make([]float32, pixels)
+	r.ssaoNormals = make([]nomath.Vec3, pixels)
+	r.ssaoAlbedo = make([]lookdev.ColorHDR, pixels)
+}
+
+// writeSSAOSample stores the world normal and diffuse color of a fragment so
+// the SSAO pass can find its orientation and take its ambient light back out
+func (r *Renderer3D) writeSSAOSample(i int, normal nomath.Vec3, albedo *lookdev.ColorRGBA) {
+	r.ssaoNormals[i] = normal
+	r.ssaoAlbedo[i] = albedo.Linear()
+}
+
+// generateSSAOKernel returns samples in the +Z hemisphere, packed more
+// densely toward the origin so nearby occluders count the most
+func generateSSAOKernel(samples int) []nomath.Vec3 {
+	rng := rand.New(rand.NewSource(1))
+	kernel := make([]nomath.Vec3, samples)
+	for i := range kernel {
+		sample := nomath.Vec3{
+			X: rng.Float64()*2 - 1,
+			Y: rng.Float64()*2 - 1,
+			Z: rng.Float64(),
+		}.Normalize().Multiply(rng.Float64())
+
+		scale := float64(i) / float64(samples)
+		scale = 0.1 + 0.9*scale*scale
+		kernel[i] = sample.Multiply(scale)
+	}
+	return kernel
+}
+
+// generateSSAONoise builds a tile of random rotations around the Z axis,
+// stored in the R and G channels, used to rotate the kernel per pixel
+func generateSSAONoise(size int) *lookdev.Texture {
+	rng := rand.New(rand.NewSource(2))
+	pixels := make([]lookdev.ColorRGBA, size*size)
+	for i := range pixels {
+		pixels[i] = lookdev.ColorRGBA{
+			R: uint8(rng.Intn(256)),
+			G: uint8(rng.Intn(256)),
+			B: 0,
+			A: 1.0,
+		}
+	}
+	return &lookdev.Texture{Width: size, Height: size, Pixels: pixels}
+}
+
+// ApplySSAO computes screen-space ambient occlusion from the depth and normal
+// buffers, blurs it and darkens the ambient part of every shaded pixel by it.
+func (r *Renderer3D) ApplySSAO(camera *PerspectiveCamera) {
+	if !r.SSAOEnabled || len(r.ssaoNormals) != len(r.DepthBuffer) {
+		return
+	}
+
+	view := camera.Transform.GetMatrix().Inverse()
+	projection := camera.GetProjectionMatrix()
+	invProjection := projection.Inverse()
+
+	parallelFor(r.GetHeight(), r.workers, func(y int) {
+		r.computeOcclusionRow(y, view, projection, invProjection)
+	})
+	parallelFor(r.GetHeight(), r.workers, r.blurOcclusionRow)
+	parallelFor(r.GetHeight(), r.workers, r.applyOcclusionRow)
+}
+
+func (r *Renderer3D) computeOcclusionRow(y int, view, projection, invProjection nomath.Mat4) {
+	width, height := r.GetWidth(), r.GetHeight()
+	noise := r.SSAONoiseTexture
+	noiseScale := max(1.0, r.SSAONoiseScale)
+
+	for x := 0; x < width; x++ {
+		i := r.pixelIndex(x, y)
+		r.SSAOBuffer[i] = 1
+		depth := r.DepthBuffer[i]
+		if depth > 1 {
+			continue
+		}
+
+		position := r.viewPosition(float64(x)+0.5, float64(y)+0.5, float64(depth), invProjection)
+		normal := view.TransformVec3(r.ssaoNormals[i]).Normalize()
+		if normal.Dot(position) > 0 {
+			// Facing away from the camera (flipped normals), so the kernel
+			// would end up inside the surface
+			normal = normal.Negate()
+		}
+
+		// Rotate the kernel by the noise vector and orient it along the normal
+		nx := int(float64(x)/noiseScale) % noise.Width
+		ny := int(float64(y)/noiseScale) % noise.Height
+		n := noise.Pixels[ny*noise.Width+nx]
+		random := nomath.Vec3{X: float64(n.R)/127.5 - 1, Y: float64(n.G)/127.5 - 1}
+		tangent := random.Subtract(normal.Multiply(random.Dot(normal)))
+		if tangent.LengthSquared() < 1e-8 {
+			tangent = nomath.Vec3{X: 1}.Subtract(normal.Multiply(normal.X))
+		}
+		tangent = tangent.Normalize()
+		bitangent := normal.Cross(tangent)
+
+		occlusion := 0.0
+		for _, k := range r.SSAOKernel {
+			offset := tangent.Multiply(k.X).Add(bitangent.Multiply(k.Y)).Add(normal.Multiply(k.Z))
+			sample := position.Add(offset.Multiply(r.SSAORadius))
+
+			clip := projection.MultiplyVec4(sample.ToVec4(1.0))
+			if clip.W <= 0 {
+				continue
+			}
+			ndc := clip.ToVec3()
+			sx := int((ndc.X + 1) * 0.5 * float64(width))
+			sy := int((1 - (ndc.Y+1)*0.5) * float64(height))
+			if sx < 0 || sx >= width || sy < 0 || sy >= height {
+				continue
+			}
+			sampleDepth := r.DepthBuffer[r.pixelIndex(sx, sy)]
+			if sampleDepth > 1 {
+				continue
+			}
+
+			// View space looks down -Z, so larger Z is closer to the camera
+			sceneZ := r.viewPosition(float64(sx)+0.5, float64(sy)+0.5, float64(sampleDepth), invProjection).Z
+			if sceneZ >= sample.Z+r.SSAOBias {
+				rangeCheck := smoothstep(0, 1, r.SSAORadius/math.Abs(position.Z-sceneZ))
+				occlusion += rangeCheck
+			}
+		}
+		r.SSAOBuffer[i] = float32(1 - occlusion/float64(max(1, len(r.SSAOKernel))))
+	}
+}
+
+// blurOcclusionRow averages the occlusion over the noise tile, which removes
+// the pattern the rotated kernels leave behind
+func (r *Renderer3D) blurOcclusionRow(y int) {
+	width, height := r.GetWidth(), r.GetHeight()
+	half := ssaoNoiseSize / 2
+	for x := 0; x < width; x++ {
+		i := r.pixelIndex(x, y)
+		if r.DepthBuffer[i] > 1 {
+			r.ssaoBlurred[i] = 1
+			continue
+		}
+		sum, count := float32(0), 0
+		for dy := -half; dy < ssaoNoiseSize-half; dy++ {
+			sy := y + dy
+			if sy < 0 || sy >= height {
+				continue
+			}
+			for dx := -half; dx < ssaoNoiseSize-half; dx++ {
+				sx := x + dx
+				if sx < 0 || sx >= width || r.DepthBuffer[r.pixelIndex(sx, sy)] > 1 {
+					continue
+				}
+				sum += r.SSAOBuffer[r.pixelIndex(sx, sy)]
+				count++
+			}
+		}
+		r.ssaoBlurred[i] = sum / float32(max(1, count))
+	}
+}
+
+// applyOcclusionRow removes the occluded share of the ambient term
+func (r *Renderer3D) applyOcclusionRow(y int) {
+	for x := 0; x < r.GetWidth(); x++ {
+		i := r.pixelIndex(x, y)
+		if r.DepthBuffer[i] > 1 {
+			continue
+		}
+		occluded := r.ambienceFactor * float64(1-r.ssaoBlurred[i])
+		if occluded <= 0 {
+			continue
+		}
+		albedo := r.ssaoAlbedo[i]
+		if r.samples > 1 {
+			// Only the samples with geometry, not the background at edges
+			for s := i * r.samples; s < (i+1)*r.samples; s++ {
+				if r.sampleDepth[s] <= 1 {
+					darkenRGB(r.sampleColor[s*3:s*3+3:s*3+3], albedo, occluded)
+				}
+			}
+			continue
+		}
+		darkenRGB(r.ColorBuffer[i*3:i*3+3:i*3+3], albedo, occluded)
+	}
+}
+
+func darkenRGB(pixel []float32, albedo lookdev.ColorHDR, factor float64) {
+	f := float32(factor)
+	pixel[0] = max(0, pixel[0]-albedo.R*f)
+	pixel[1] = max(0, pixel[1]-albedo.G*f)
+	pixel[2] = max(0, pixel[2]-albedo.B*f)
+}
+
+// viewPosition reconstructs a view-space position from a screen position and
+// the depth buffer value stored there
+func (r *Renderer3D) viewPosition(x, y, depth float64, invProjection nomath.Mat4) nomath.Vec3 {
+	ndc := nomath.Vec4{
+		X: x/float64(r.GetWidth())*2 - 1,
+		Y: 1 - y/float64(r.GetHeight())*2,
+		Z: depth*2 - 1,
+		W: 1,
+	}
+	return invProjection.MultiplyVec4(ndc).ToVec3()
+}
+
+func smoothstep(edge0, edge1, x float64) float64 {
+	t := math.Max(0, math.Min(1, (x-edge0)/(edge1-edge0)))
+	return t * t * (3 - 2*t)
+}
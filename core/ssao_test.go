@@ -0,0 +1,31 @@
+package core
+
+import "testing"
+
+func TestGenerateSSAOKernel(t *testing.T) {
+	kernel := generateSSAOKernel(32)
+	if len(kernel) != 32 {
+		t.Fatalf("got %d samples, want 32", len(kernel))
+	}
+	for i, sample := range kernel {
+		if sample.Z < 0 || sample.Length() > 1 {
+			t.Errorf("sample %d at %v is outside the unit +Z hemisphere", i, sample)
+		}
+	}
+	// The later samples reach further out, so nearby occluders count the most
+	near, far := 0.0, 0.0
+	for i := 0; i < 8; i++ {
+		near += kernel[i].Length()
+		far += kernel[len(kernel)-1-i].Length()
+	}
+	if near >= far {
+		t.Errorf("first samples reach %v on average, last %v", near/8, far/8)
+	}
+
+	again := generateSSAOKernel(32)
+	for i := range kernel {
+		if kernel[i] != again[i] {
+			t.Fatal("the kernel changes between calls, so the occlusion would flicker")
+		}
+	}
+}
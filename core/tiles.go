@@ -0,0 +1,165 @@
+package core
+
+import (
+	"image"
+	"sync"
+	"sync/atomic"
+)
+
+// tileSize is the width and height of a screen tile in pixels
+const tileSize = 32
+
+// prepareBatch is how many tasks a worker sets up at a time
+const prepareBatch = 64
+
+// arenaChunk is how many setups or screen triangles an arena allocates at once
+const arenaChunk = 64
+
+// triangleArena holds the setups and screen triangles made from one batch of
+// tasks. They are kept in fixed-size chunks, so pointers to them stay valid as
+// the arena grows, and are handed out again after reset instead of being
+// reallocated every pass.
+type triangleArena struct {
+	setups      [][]triangleSetup
+	screens     [][]screenTriangle
+	setupCount  int
+	screenCount int
+	out         []*screenTriangle // The batch's screen triangles in submission order
+}
+
+func (a *triangleArena) reset() {
+	a.setupCount, a.screenCount = 0, 0
+	clear(a.out)
+	a.out = a.out[:0]
+}
+
+// newSetup returns an unused setup, still holding whatever it held last pass
+func (a *triangleArena) newSetup() *triangleSetup {
+	chunk, i := a.setupCount/arenaChunk, a.setupCount%arenaChunk
+	if chunk == len(a.setups) {
+		a.setups = append(a.setups, make([]triangleSetup, arenaChunk))
+	}
+	a.setupCount++
+	return &a.setups[chunk][i]
+}
+
+// addScreen copies st into the arena and appends it to out
+func (a *triangleArena) addScreen(st screenTriangle) {
+	chunk, i := a.screenCount/arenaChunk, a.screenCount%arenaChunk
+	if chunk == len(a.screens) {
+		a.screens = append(a.screens, make([]screenTriangle, arenaChunk))
+	}
+	a.screenCount++
+	a.screens[chunk][i] = st
+	a.out = append(a.out, &a.screens[chunk][i])
+}
+
+// tileBins holds, for every screen tile, the triangles overlapping it in
+// submission order. Each tile is rasterized by a single worker, so no two
+// goroutines ever touch the same pixel.
+type tileBins struct {
+	cols, rows int
+	bins       [][]*screenTriangle
+}
+
+// reset empties the bins, reallocating them when the screen size changed
+func (t *tileBins) reset(width, height int) {
+	cols := (width + tileSize - 1) / tileSize
+	rows := (height + tileSize - 1) / tileSize
+	if cols != t.cols || rows != t.rows {
+		t.cols, t.rows = cols, rows
+		t.bins = make([][]*screenTriangle, cols*rows)
+		return
+	}
+	for i := range t.bins {
+		clear(t.bins[i])
+		t.bins[i] = t.bins[i][:0]
+	}
+}
+
+// add bins st into every tile its bounds overlap. Bounds reaching past the
+// screen only go into the edge tiles.
+func (t *tileBins) add(st *screenTriangle) {
+	minX, maxX := max(0, st.bounds.Min.X/tileSize), min(t.cols-1, st.bounds.Max.X/tileSize)
+	minY, maxY := max(0, st.bounds.Min.Y/tileSize), min(t.rows-1, st.bounds.Max.Y/tileSize)
+	for ty := minY; ty <= maxY; ty++ {
+		for tx := minX; tx <= maxX; tx++ {
+			i := ty*t.cols + tx
+			t.bins[i] = append(t.bins[i], st)
+		}
+	}
+}
+
+// tileRect returns the pixels of tile i, with Max inclusive
+func (t *tileBins) tileRect(i int) image.Rectangle {
+	x, y := (i%t.cols)*tileSize, (i/t.cols)*tileSize
+	return image.Rect(x, y, x+tileSize-1, y+tileSize-1)
+}
+
+// renderTasks draws tasks with up to workers goroutines. Triangles are set up
+// and clipped in parallel, binned into screen tiles, and then each tile is
+// rasterized by one worker in submission order, so the result doesn't depend
+// on the number of workers.
+func (r *Renderer3D) renderTasks(tasks []RenderTask, camera *PerspectiveCamera, lights []*Light, workers int) {
+	if len(tasks) == 0 {
+		return
+	}
+	workers = max(1, workers)
+
+	// Set up in batches, keeping each batch's output in its own arena so the
+	// triangles can be binned in the order they were submitted
+	batches := (len(tasks) + prepareBatch - 1) / prepareBatch
+	for len(r.arenas) < batches {
+		r.arenas = append(r.arenas, triangleArena{})
+	}
+	parallelFor(batches, workers, func(batch int) {
+		arena := &r.arenas[batch]
+		arena.reset()
+		for i := batch * prepareBatch; i < min(len(tasks), (batch+1)*prepareBatch); i++ {
+			r.prepareTriangle(&tasks[i], camera, lights, arena)
+		}
+	})
+
+	r.tiles.reset(r.GetWidth(), r.GetHeight())
+	for _, arena := range r.arenas[:batches] {
+		for _, st := range arena.out {
+			r.tiles.add(st)
+		}
+	}
+
+	parallelFor(len(r.tiles.bins), workers, func(tile int) {
+		area := r.tiles.tileRect(tile)
+		for _, st := range r.tiles.bins[tile] {
+			r.rasterizeTriangle(st, area, lights, camera)
+		}
+	})
+}
+
+// parallelFor calls fn for every index below n, handing indices out to up to
+// workers goroutines as they become free
+func parallelFor(n, workers int, fn func(i int)) {
+	workers = min(workers, n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
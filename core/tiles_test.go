@@ -0,0 +1,172 @@
+package core
+
+import (
+	"GopherEngine/assets"
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"bytes"
+	"fmt"
+	"image"
+	"testing"
+)
+
+// screenTasks builds render tasks for triangles given in pixel coordinates,
+// with the vertex stage output filled in directly
+func screenTasks(width, height int, triangles [][3]nomath.Vec2) []RenderTask {
+	geom := (&assets.Geometry{}).NewGeometry()
+	vertices := &TransformedMesh{ModelMatrix: nomath.IdentityMatrix()}
+	for _, corners := range triangles {
+		base := uint32(len(geom.Mesh.Positions))
+		for _, p := range corners {
+			ndc := nomath.Vec3{X: p.U/float64(width)*2 - 1, Y: 1 - p.V/float64(height)*2}
+			geom.Mesh.Positions = append(geom.Mesh.Positions, ndc)
+			geom.Mesh.Normals = append(geom.Mesh.Normals, nomath.Vec3{})
+			vertices.Clip = append(vertices.Clip, ndc.ToVec4(1))
+			vertices.World = append(vertices.World, ndc)
+		}
+		geom.Triangles = append(geom.Triangles, assets.NewTriangle(geom, geom.Material, base, base+1, base+2))
+	}
+	geom.PrecomputeTextureBuffers()
+
+	tasks := make([]RenderTask, len(geom.Triangles))
+	for i, tri := range geom.Triangles {
+		tasks[i] = RenderTask{Triangle: tri, Vertices: vertices}
+	}
+	return tasks
+}
+
+func TestTileBins(t *testing.T) {
+	var bins tileBins
+	bins.reset(70, 40) // Three columns and two rows, the last ones partial
+	if bins.cols != 3 || bins.rows != 2 {
+		t.Fatalf("got %dx%d tiles, want 3x2", bins.cols, bins.rows)
+	}
+
+	st := &screenTriangle{bounds: image.Rect(20, 10, 40, 35)}
+	bins.add(st)
+	for i, bin := range bins.bins {
+		want := i == 0 || i == 1 || i == 3 || i == 4
+		if got := len(bin) == 1 && bin[0] == st; got != want {
+			t.Errorf("tile %d holds %d triangles, want the triangle %v", i, len(bin), want)
+		}
+	}
+	if got := bins.tileRect(4); got != image.Rect(32, 32, 63, 63) {
+		t.Errorf("tile 4 covers %v, want (32,32)-(63,63)", got)
+	}
+
+	bins.reset(70, 40)
+	for i, bin := range bins.bins {
+		if len(bin) != 0 {
+			t.Errorf("tile %d still holds %d triangles after reset", i, len(bin))
+		}
+	}
+}
+
+func TestRenderWorkersMatch(t *testing.T) {
+	render := func(workers int) *image.RGBA {
+		s := newTestScene()
+		// Overlapping quads across tile borders, more than one setup batch of
+		// them, with every third one blended over the others
+		for i := 0; i < 120; i++ {
+			x, y := -2+float64(i%12)*0.3, -1.2+float64(i/12)*0.2
+			c := lookdev.ColorRGBA{R: uint8(i * 2), G: uint8(255 - i*2), B: uint8(i % 3 * 120), A: 1}
+			if i%3 == 0 {
+				c.A = 0.5
+			}
+			s.AddObject(testQuad(t, x, y, x+0.7, y+0.7, -float64(i%7)*0.2, flatMaterial("quad", c)))
+		}
+		s.RenderFrame(150, 100)
+		s.Renderer.Clear(s.Background)
+		s.render(workers)
+		return s.Renderer.ToImage()
+	}
+
+	want := render(1)
+	background := want.RGBAAt(0, 0)
+	covered := 0
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 150; x++ {
+			if want.RGBAAt(x, y) != background {
+				covered++
+			}
+		}
+	}
+	if covered < 150*100/4 {
+		t.Fatalf("only %d pixels were drawn", covered)
+	}
+
+	for _, workers := range []int{2, 7} {
+		if got := render(workers); !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("%d workers rendered a different image than one", workers)
+		}
+	}
+}
+
+func TestRenderTasksScreenEdge(t *testing.T) {
+	sizes := []struct{ width, height int }{
+		{320, 240}, {640, 480}, {854, 480}, {800, 600},
+	}
+	for _, size := range sizes {
+		t.Run(fmt.Sprintf("%dx%d", size.width, size.height), func(t *testing.T) {
+			w, h := float64(size.width), float64(size.height)
+
+			// Slivers along the right and bottom edges, some between the
+			// last pixel centres and the edge, where they cover no pixels
+			var triangles [][3]nomath.Vec2
+			for offset := -1.0; offset <= 0.5; offset += 0.1 {
+				x, y := w+offset, h+offset
+				triangles = append(triangles,
+					[3]nomath.Vec2{{U: x, V: 10}, {U: w, V: 10}, {U: w, V: h - 10}},
+					[3]nomath.Vec2{{U: 10, V: y}, {U: w - 10, V: h}, {U: 10, V: h}},
+					[3]nomath.Vec2{{U: x, V: y}, {U: w, V: y}, {U: w, V: h}},
+				)
+			}
+
+			r := NewRenderer3D()
+			r.Resize(size.width, size.height)
+			r.Clear(lookdev.ColorRGBA{A: 1})
+			for _, workers := range []int{1, 4} {
+				r.renderTasks(screenTasks(size.width, size.height, triangles), NewPerspectiveCamera(), nil, workers)
+			}
+
+			// Whatever is left after setup must lie on the screen, inclusive of Max
+			tasks := screenTasks(size.width, size.height, triangles)
+			var arena triangleArena
+			for i := range tasks {
+				r.prepareTriangle(&tasks[i], NewPerspectiveCamera(), nil, &arena)
+			}
+			screen := image.Rect(0, 0, size.width, size.height)
+			for i, st := range arena.out {
+				b := st.bounds
+				if b.Min.X > b.Max.X || b.Min.Y > b.Max.Y || !b.Min.In(screen) || !b.Max.In(screen) {
+					t.Errorf("triangle %d: bounds %v outside the %dx%d screen", i, b, size.width, size.height)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderAllocations(t *testing.T) {
+	allocs := func(quads int) float64 {
+		s := newTestScene()
+		for i := 0; i < quads; i++ {
+			x, y := -2+float64(i%20)*0.2, -1.2+float64(i/20)*0.2
+			c := lookdev.ColorRGBA{R: 200, G: 100, B: 50, A: 1}
+			if i%4 == 0 {
+				c.A = 0.5
+			}
+			s.AddObject(testQuad(t, x, y, x+0.3, y+0.3, -float64(i%5)*0.1, flatMaterial("quad", c)))
+		}
+		// The first frame sizes the buffers and pools that later frames reuse
+		s.RenderFrame(120, 80)
+		return testing.AllocsPerRun(5, func() {
+			s.Renderer.Clear(s.Background)
+			s.render(1)
+		})
+	}
+
+	few, many := allocs(20), allocs(200)
+	if many > few {
+		t.Errorf("a frame of 200 quads made %v allocations and one of 20 made %v, want no more per triangle", many, few)
+	}
+}
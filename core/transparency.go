@@ -0,0 +1,58 @@
+package core
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"cmp"
+	"math"
+	"slices"
+)
+
+// transparentTask is a blended triangle waiting for the opaque pass to finish
+type transparentTask struct {
+	task  RenderTask
+	depth float64 // Distance from the camera along its view direction
+}
+
+func (s *Scene) newTransparentTask(task RenderTask, centroid nomath.Vec3) transparentTask {
+	task.Transparent = true
+	eye := s.Camera.Transform.Position
+	return transparentTask{
+		task:  task,
+		depth: centroid.Subtract(eye).Dot(s.Camera.Transform.GetForward()),
+	}
+}
+
+// renderTransparent blends transparent triangles over the opaque image from
+// back to front. Blending depends on draw order, which the tiles keep.
+func (s *Scene) renderTransparent(tasks []transparentTask, workers int) {
+	slices.SortStableFunc(tasks, func(a, b transparentTask) int {
+		return cmp.Compare(b.depth, a.depth)
+	})
+	s.sortedTasks = s.sortedTasks[:0]
+	for i := range tasks {
+		s.sortedTasks = append(s.sortedTasks, tasks[i].task)
+	}
+	s.Renderer.renderTasks(s.sortedTasks, s.Camera, s.Lights, workers)
+}
+
+// blendPixel composites color over the pixel at index i with the given
+// opacity, in linear light
+func (r *Renderer3D) blendPixel(i int, color lookdev.ColorHDR, alpha float64) {
+	blendRGB(r.ColorBuffer[i*3:i*3+3:i*3+3], color, alpha)
+}
+
+// blendSample is blendPixel for MSAA sample s
+func (r *Renderer3D) blendSample(s int, color lookdev.ColorHDR, alpha float64) {
+	blendRGB(r.sampleColor[s*3:s*3+3:s*3+3], color, alpha)
+}
+
+func blendRGB(dst []float32, color lookdev.ColorHDR, alpha float64) {
+	if alpha <= 0 {
+		return
+	}
+	a := float32(math.Min(1, alpha))
+	dst[0] += (color.R - dst[0]) * a
+	dst[1] += (color.G - dst[1]) * a
+	dst[2] += (color.B - dst[2]) * a
+}
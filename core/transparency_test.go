@@ -0,0 +1,124 @@
+package core
+
+import (
+	"GopherEngine/assets"
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"fmt"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// newTestScene returns an unlit scene without overlays on black, so surfaces
+// show their material colors, with the camera on +Z looking at the origin
+func newTestScene() *Scene {
+	s := NewScene()
+	s.Lights = nil
+	s.Grid.Enabled = false
+	s.ViewAxes.Enabled = false
+	s.Background = lookdev.ColorRGBA{A: 1}
+	s.Camera.Transform.SetPosition(nomath.Vec3{Z: 3})
+	s.Camera.Transform.SetRotation(nomath.Vec3{})
+	return s
+}
+
+// testQuad is a rectangle facing +Z at depth z with the given material,
+// with UVs running from 0 to 1 across it
+func testQuad(t *testing.T, minX, minY, maxX, maxY, z float64, material *lookdev.Material) *assets.Geometry {
+	t.Helper()
+	geom := loadTestOBJ(t, fmt.Sprintf(`v %[1]g %[2]g %[5]g
+v %[3]g %[2]g %[5]g
+v %[3]g %[4]g %[5]g
+v %[1]g %[4]g %[5]g
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+f 1/1 2/2 3/3 4/4
+`, minX, minY, maxX, maxY, z))
+	geom.Material = material
+	for _, tri := range geom.Triangles {
+		tri.Material = material
+	}
+	return geom
+}
+
+func flatMaterial(name string, c lookdev.ColorRGBA) *lookdev.Material {
+	m := lookdev.NewMaterial(name)
+	m.DiffuseColor = c
+	return m
+}
+
+func TestSortedTransparency(t *testing.T) {
+	red := flatMaterial("red", lookdev.ColorRGBA{R: 255, A: 0.5})
+	blue := flatMaterial("blue", lookdev.ColorRGBA{B: 255, A: 0.5})
+
+	render := func(nearFirst bool) (center, farOnly, nearOnly color.RGBA) {
+		s := newTestScene()
+		far := testQuad(t, -2, -1, 1, 1, -1, red)
+		near := testQuad(t, -0.5, -1, 1.5, 1, 0, blue)
+		if nearFirst {
+			s.AddObject(near)
+			s.AddObject(far)
+		} else {
+			s.AddObject(far)
+			s.AddObject(near)
+		}
+		s.RenderFrame(64, 64)
+		img := s.Renderer.ToImage()
+		return img.RGBAAt(32, 32), img.RGBAAt(15, 32), img.RGBAAt(50, 32)
+	}
+
+	var centers []color.RGBA
+	for _, nearFirst := range []bool{true, false} {
+		center, farOnly, nearOnly := render(nearFirst)
+		if center.B <= center.R || center.R == 0 {
+			t.Errorf("near first %v: overlap is %v, want blue over the red showing through", nearFirst, center)
+		}
+		if farOnly.R == 0 || farOnly.B != 0 {
+			t.Errorf("near first %v: red alone is %v", nearFirst, farOnly)
+		}
+		if nearOnly.B == 0 || nearOnly.R != 0 {
+			t.Errorf("near first %v: blue alone is %v", nearFirst, nearOnly)
+		}
+		centers = append(centers, center)
+	}
+	if centers[0] != centers[1] {
+		t.Errorf("the overlap is %v or %v depending on the order the objects were added in", centers[0], centers[1])
+	}
+}
+
+func TestTransparencyBehindOpaque(t *testing.T) {
+	s := newTestScene()
+	s.AddObject(testQuad(t, -1, -1, 1, 1, 0, flatMaterial("green", lookdev.ColorRGBA{G: 255, A: 1})))
+	s.AddObject(testQuad(t, -1, -1, 1, 1, -1, flatMaterial("red", lookdev.ColorRGBA{R: 255, A: 0.5})))
+	s.RenderFrame(64, 64)
+	if got := s.Renderer.ToImage().RGBAAt(32, 32); got.R != 0 || got.G == 0 {
+		t.Errorf("got %v, want the opaque green in front to hide the red", got)
+	}
+}
+
+func TestBlendRGB(t *testing.T) {
+	tests := []struct {
+		alpha float64
+		want  [3]float32
+	}{
+		{0, [3]float32{0.2, 0.4, 0.1}},
+		{-1, [3]float32{0.2, 0.4, 0.1}},
+		{1, [3]float32{2, 0, 0.5}},
+		{1.5, [3]float32{2, 0, 0.5}},
+		{0.5, [3]float32{1.1, 0.2, 0.3}},
+		{0.25, [3]float32{0.65, 0.3, 0.2}},
+	}
+	for _, tt := range tests {
+		dst := []float32{0.2, 0.4, 0.1}
+		blendRGB(dst, lookdev.ColorHDR{R: 2, G: 0, B: 0.5}, tt.alpha)
+		for c := range dst {
+			if math.Abs(float64(dst[c]-tt.want[c])) > 1e-6 {
+				t.Errorf("blending at alpha %v gave %v, want %v", tt.alpha, dst, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"GopherEngine/assets"
+	"GopherEngine/nomath"
+)
+
+// TransformedMesh is a Geometry's Mesh run through the vertex stage for the
+// current frame. Triangles index into it, so a vertex shared by several
+// triangles is transformed once instead of once per triangle.
+type TransformedMesh struct {
+	ModelMatrix  nomath.Mat4
+	NormalMatrix nomath.Mat4 // Inverse transpose of ModelMatrix
+	MVP          nomath.Mat4
+
+	Clip       []nomath.Vec4 // Clip-space positions
+	World      []nomath.Vec3 // World-space positions
+	Normals    []nomath.Vec3 // World-space unit normals, zero where the mesh has none
+	Tangents   []nomath.Vec3 // World-space tangent frames, empty without tangents
+	Bitangents []nomath.Vec3
+
+	frame uint64 // Last frame its Geometry was in the scene
+}
+
+// transformVertices runs geom's mesh through the vertex stage, reusing the
+// buffers from the previous frame. Normals and tangents are skipped when
+// flat shading, since triangle setup doesn't read them then.
+func (s *Scene) transformVertices(geom *assets.Geometry, viewProj nomath.Mat4) *TransformedMesh {
+	if s.transformed == nil {
+		s.transformed = make(map[*assets.Geometry]*TransformedMesh)
+	}
+	tm := s.transformed[geom]
+	if tm == nil {
+		tm = &TransformedMesh{}
+		s.transformed[geom] = tm
+	}
+
+	mesh := geom.Mesh
+	tm.ModelMatrix = geom.Transform.GetMatrix()
+	tm.NormalMatrix = tm.ModelMatrix.Inverse().Transpose()
+	tm.MVP = viewProj.Multiply(tm.ModelMatrix)
+
+	tm.Clip = tm.Clip[:0]
+	tm.World = tm.World[:0]
+	for _, p := range mesh.Positions {
+		position := p.ToVec4(1.0)
+		tm.Clip = append(tm.Clip, tm.MVP.MultiplyVec4(position))
+		tm.World = append(tm.World, tm.ModelMatrix.MultiplyVec4(position).ToVec3())
+	}
+
+	tm.Normals = tm.Normals[:0]
+	tm.Tangents = tm.Tangents[:0]
+	tm.Bitangents = tm.Bitangents[:0]
+	if s.Renderer.ShadingMode == ShadingFlat {
+		return tm
+	}
+	for _, n := range mesh.Normals {
+		tm.Normals = append(tm.Normals, tm.NormalMatrix.TransformVec3(n).Normalize())
+	}
+	for i := range mesh.Tangents {
+		tm.Tangents = append(tm.Tangents, tm.ModelMatrix.TransformVec3(mesh.Tangents[i]).Normalize())
+		tm.Bitangents = append(tm.Bitangents, tm.ModelMatrix.TransformVec3(mesh.Bitangents[i]).Normalize())
+	}
+	return tm
+}
+
+// pruneTransformed drops the vertex stage buffers of geometry that has been
+// removed from the scene
+func (s *Scene) pruneTransformed() {
+	s.transformedFrame++
+	for _, obj := range s.Objects {
+		if tm := s.transformed[obj]; tm != nil {
+			tm.frame = s.transformedFrame
+		}
+	}
+	for geom, tm := range s.transformed {
+		if tm.frame != s.transformedFrame {
+			delete(s.transformed, geom)
+		}
+	}
+}
+
+// centroid returns the world-space centroid of tri
+func (tm *TransformedMesh) centroid(tri *assets.Triangle) nomath.Vec3 {
+	return tm.ModelMatrix.MultiplyVec4(tri.Centroid().ToVec4(1.0)).ToVec3()
+}
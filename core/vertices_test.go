@@ -0,0 +1,66 @@
+package core
+
+import (
+	"GopherEngine/lookdev"
+	"GopherEngine/nomath"
+	"math"
+	"testing"
+)
+
+func TestTransformVertices(t *testing.T) {
+	s := newTestScene()
+	s.Renderer.ShadingMode = ShadingPhong
+	geom := testQuad(t, -1, -1, 1, 1, 0, flatMaterial("grey", lookdev.ColorRGBA{R: 128, G: 128, B: 128, A: 1}))
+	geom.Transform.SetPosition(nomath.Vec3{X: 2})
+	geom.Transform.SetScale(nomath.Vec3{X: 2, Y: 2, Z: 2})
+	viewProj := nomath.TranslationMatrix(0, 0, -5)
+
+	tm := s.transformVertices(geom, viewProj)
+	// The two triangles share the quad's diagonal, so only its four corners are transformed
+	if len(tm.World) != 4 || len(tm.Clip) != 4 || len(tm.Normals) != 4 {
+		t.Fatalf("got %d world, %d clip and %d normals, want one per vertex", len(tm.World), len(tm.Clip), len(tm.Normals))
+	}
+	for i, p := range geom.Mesh.Positions {
+		want := nomath.Vec3{X: 2 + 2*p.X, Y: 2 * p.Y, Z: 2 * p.Z}
+		if tm.World[i] != want {
+			t.Errorf("vertex %d at %v, want %v", i, tm.World[i], want)
+		}
+		if clip := tm.Clip[i]; clip != (nomath.Vec4{X: want.X, Y: want.Y, Z: want.Z - 5, W: 1}) {
+			t.Errorf("vertex %d clip position %v, want the world position moved by viewProj", i, clip)
+		}
+		if n := tm.Normals[i]; math.Abs(n.Z-1) > 1e-9 || math.Abs(n.Length()-1) > 1e-9 {
+			t.Errorf("vertex %d normal %v, want unit +Z despite the scale", i, n)
+		}
+	}
+
+	// The next frame reuses the same buffers
+	world := &tm.World[0]
+	if again := s.transformVertices(geom, viewProj); again != tm || &again.World[0] != world {
+		t.Error("the vertex stage allocated new buffers for the same geometry")
+	}
+
+	s.Renderer.ShadingMode = ShadingFlat
+	if tm := s.transformVertices(geom, viewProj); len(tm.Normals) != 0 || len(tm.World) != 4 {
+		t.Errorf("flat shading transformed %d normals, want none", len(tm.Normals))
+	}
+}
+
+func TestPruneTransformed(t *testing.T) {
+	s := newTestScene()
+	grey := flatMaterial("grey", lookdev.ColorRGBA{R: 128, G: 128, B: 128, A: 1})
+	kept := testQuad(t, -1, -1, 0, 1, 0, grey)
+	removed := testQuad(t, 0, -1, 1, 1, 0, grey)
+	s.AddObject(kept)
+	s.AddObject(removed)
+
+	s.RenderFrame(32, 32)
+	if s.transformed[kept] == nil || s.transformed[removed] == nil {
+		t.Fatalf("got vertex stage buffers for %d objects, want both", len(s.transformed))
+	}
+
+	s.Objects = s.Objects[:1]
+	s.RenderFrame(32, 32)
+	if len(s.transformed) != 1 || s.transformed[kept] == nil {
+		t.Errorf("got vertex stage buffers for %d objects, want only the one left in the scene", len(s.transformed))
+	}
+}
@@ -89,13 +89,14 @@ func Window(scene *core.Scene) {
 		HandleInputEvents(scene)
 
 		// Render 3D scene
+		scene.Renderer.Clear(scene.Background)
 		scene.ViewAxes.Draw(scene.Renderer, scene.Camera)
 		scene.Grid.Draw(scene.Renderer, scene.Camera)
-		scene.RenderScene()
+		scene.RenderOnThread()
 
-		// Get rendered image and convert to RGBA
+		// Get rendered image, viewed in place as raylib pixels
 		rawImage := scene.Renderer.ToImage()
-		rgbaSlice := convertToColorRGBASlice(rawImage)
+		rgbaSlice := colorRGBAView(rawImage)
 
 		// Check if we need to resize texture
 		imgWidth := rawImage.Bounds().Dx()
@@ -147,6 +148,11 @@ func updateTargetResolution(scene *core.Scene, currentFPS int, currentTime float
 	newTarget := scene.MinResolutionScale +
 		(1.0-scene.MinResolutionScale)*fpsRatio*fpsRatio
 
+	// Spend frame time left over above maxFPS on supersampling
+	if spare := (float64(currentFPS) - maxFPS) / maxFPS; spare > 0 {
+		newTarget = 1.0 + (scene.MaxResolutionScale-1.0)*math.Min(1.0, spare)
+	}
+
 	// Only update target if significantly different
 	if math.Abs(newTarget-scene.TargetResolutionScale) > 0.05 {
 		scene.TargetResolutionScale = newTarget
@@ -172,7 +178,7 @@ func adjustResolutionGradually(scene *core.Scene, frameTime float64) {
 
 	// Ensure we stay within bounds
 	scene.ResolutionScale = math.Max(scene.MinResolutionScale,
-		math.Min(1.0, scene.ResolutionScale))
+		math.Min(math.Max(1.0, scene.MaxResolutionScale), scene.ResolutionScale))
 
 	// Resize will happen in next handleWindowResize call
 }
@@ -190,24 +196,27 @@ func draw_debug_stats(scene *core.Scene) {
 		avgFPS = scene.FPSSum / len(scene.FPSHistory)
 	}
 
-	statsText := fmt.Sprintf("%s\nFPS: %d (Avg: %d)\nResolution: %.0f%% (Target: %.0f%%)\nAuto-Res: %v\nScene Triangles : %v/%v",
+	statsText := fmt.Sprintf("%s\nFPS: %d (Avg: %d)\nResolution: %.0f%% (Target: %.0f%%)\nAuto-Res: %v\nMSAA: %dx  FXAA: %v  Tone map: %s\nScene Triangles : %v/%v",
 		core.GetMachineStats(),
 		rl.GetFPS(),
 		avgFPS,
 		scene.ResolutionScale*100,
 		scene.TargetResolutionScale*100,
 		scene.AutoResolution,
+		scene.Renderer.MSAASamples,
+		scene.Renderer.FXAAEnabled,
+		core.ToneMapName(scene.Renderer.ToneMapping),
 		scene.DrawnTriangles,
 		len(scene.Triangles))
 
 	textWidth := rl.MeasureText(statsText, 12)
-	rl.DrawRectangle(10, 10, textWidth+80, 150, rl.NewColor(0, 0, 0, 60))
+	rl.DrawRectangle(10, 10, textWidth+80, 165, rl.NewColor(0, 0, 0, 60))
 	rl.DrawTextEx(debugFont, statsText, rl.NewVector2(20, 40), 12, 2, rl.LightGray)
 
 	// Show scaling info if in auto mode
 	if scene.AutoResolution {
 		scalingText := fmt.Sprintf("Scaling: %.1f%%/s", scene.ResolutionChangeSpeed*100)
-		rl.DrawTextEx(debugFont, scalingText, rl.NewVector2(20, 140), 12, 2, rl.LightGray)
+		rl.DrawTextEx(debugFont, scalingText, rl.NewVector2(20, 155), 12, 2, rl.LightGray)
 	}
 }
 
@@ -217,35 +226,11 @@ func drawKeyboardOverlay(tex rl.Texture2D) {
 	rl.DrawTexture(tex, int32(x), int32(y), rl.White)
 }
 
-/*
-func convertToColorRGBASlice(img *image.RGBA) []color.RGBA {
-	w := img.Bounds().Dx()
-	h := img.Bounds().Dy()
-	src := img.Pix
-	pixels := make([]color.RGBA, w*h)
-
-	for i := 0; i < len(pixels); i++ {
-		pixels[i] = color.RGBA{
-			R: src[i*4],
-			G: src[i*4+1],
-			B: src[i*4+2],
-			A: src[i*4+3],
-		}
+// colorRGBAView reinterprets img's pixel bytes as a color.RGBA slice without
+// copying, for uploading straight to a texture
+func colorRGBAView(img *image.RGBA) []color.RGBA {
+	if len(img.Pix) == 0 {
+		return nil
 	}
-
-	return pixels
-}
-*/
-
-func convertToColorRGBASlice(img *image.RGBA) []color.RGBA {
-	w := img.Bounds().Dx()
-	h := img.Bounds().Dy()
-	src := img.Pix
-	pixels := make([]color.RGBA, w*h)
-
-	for i := 0; i < len(pixels); i++ {
-		pixels[i] = *(*color.RGBA)(unsafe.Pointer(&src[i*4]))
-	}
-
-	return pixels
+	return unsafe.Slice((*color.RGBA)(unsafe.Pointer(&img.Pix[0])), len(img.Pix)/4)
 }
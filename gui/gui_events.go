@@ -3,6 +3,7 @@ package gui
 import (
 	"GopherEngine/core"
 	"GopherEngine/nomath"
+	"math"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -49,6 +50,32 @@ func HandleInputEvents(scene *core.Scene) {
 		handleWindowResize(scene)
 	}
 
+	if rl.IsKeyPressed(rl.KeyF2) {
+		// Cycle flat -> gouraud -> phong
+		scene.Renderer.ShadingMode = (scene.Renderer.ShadingMode + 1) % 3
+	}
+
+	if rl.IsKeyPressed(rl.KeyF3) {
+		scene.Renderer.SSAOEnabled = !scene.Renderer.SSAOEnabled
+	}
+
+	if rl.IsKeyPressed(rl.KeyF4) {
+		// Cycle MSAA off -> 2x -> 4x -> 8x
+		scene.Renderer.MSAASamples = scene.Renderer.MSAASamples * 2 % 16
+		if scene.Renderer.MSAASamples == 0 {
+			scene.Renderer.MSAASamples = 1
+		}
+	}
+
+	if rl.IsKeyPressed(rl.KeyF5) {
+		scene.Renderer.FXAAEnabled = !scene.Renderer.FXAAEnabled
+	}
+
+	if rl.IsKeyPressed(rl.KeyF6) {
+		// Cycle exposure -> Reinhard -> ACES
+		scene.Renderer.ToneMapping = (scene.Renderer.ToneMapping + 1) % len(core.ToneMapNames)
+	}
+
 	if rl.IsWindowReady() {
 		HandleKeyboardEvents(scene)
 		HandleMouseEvents(scene)
@@ -170,9 +197,12 @@ func handleWindowResize(scene *core.Scene) {
 	core.SCREEN_WIDTH = newWidth
 	core.SCREEN_HEIGHT = newHeight
 
-	// Calculate render dimensions based on resolution scale
-	renderWidth := int(float64(newWidth) * scene.ResolutionScale)
-	renderHeight := int(float64(newHeight) * scene.ResolutionScale)
+	// Calculate render dimensions based on resolution scale. Below 1.0 the
+	// image is stretched to the window; above it the renderer supersamples
+	// and filters down to the window size.
+	renderWidth := int(float64(newWidth) * math.Min(1.0, scene.ResolutionScale))
+	renderHeight := int(float64(newHeight) * math.Min(1.0, scene.ResolutionScale))
+	scene.Renderer.Supersampling = math.Max(1.0, scene.ResolutionScale)
 
 	// Ensure minimum size
 	renderWidth = max(1, renderWidth)
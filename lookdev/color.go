@@ -44,6 +44,15 @@ func NewColorRGB(r, g, b uint8) *ColorRGBA {
 	}
 }
 
+// NewColorRGBFloat creates an opaque color from channels in [0, 1], clamping
+// values outside that range
+func NewColorRGBFloat(r, g, b float64) *ColorRGBA {
+	toByte := func(v float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+	}
+	return NewColorRGB(toByte(r), toByte(g), toByte(b))
+}
+
 // NewColorRGBA creates a color with specified RGBA values
 func NewColorRGBAValues(r, g, b uint8, a float64) (*ColorRGBA, error) {
 	if a < 0 || a > 1.0 {
@@ -198,3 +207,57 @@ func (c *ColorRGBA) Grayscale() *ColorRGBA {
 func (c *ColorRGBA) String() string {
 	return fmt.Sprintf("ColorRGBA(%d, %d, %d, %.2f)", c.R, c.G, c.B, c.A)
 }
+
+// ColorHDR is a linear color with float channels. Lighting adds up in it
+// without clamping, so channels can go well above 1 until tone mapping.
+type ColorHDR struct {
+	R, G, B float32
+}
+
+// Linear decodes the sRGB channels of the color to linear light
+func (c *ColorRGBA) Linear() ColorHDR {
+	return ColorHDR{R: srgbToLinear[c.R], G: srgbToLinear[c.G], B: srgbToLinear[c.B]}
+}
+
+// SRGBToLinear decodes an 8-bit sRGB channel to linear light, 0 to 1
+func SRGBToLinear(v uint8) float32 {
+	return srgbToLinear[v]
+}
+
+// LinearToSRGB encodes a linear channel as 8-bit sRGB, clamping it to 0 to 1
+func LinearToSRGB(v float32) uint8 {
+	if !(v > 0) {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return linearToSRGB[int(v*float32(len(linearToSRGB)-1)+0.5)]
+}
+
+var srgbToLinear = func() (table [256]float32) {
+	for i := range table {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			table[i] = float32(v / 12.92)
+		} else {
+			table[i] = float32(math.Pow((v+0.055)/1.055, 2.4))
+		}
+	}
+	return table
+}()
+
+// linearToSRGB is fine enough that every 8-bit value is reachable, even in
+// the steep part of the curve near black
+var linearToSRGB = func() (table [1 << 14]uint8) {
+	for i := range table {
+		v := float64(i) / float64(len(table)-1)
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		table[i] = uint8(math.Round(v * 255))
+	}
+	return table
+}()
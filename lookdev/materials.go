@@ -5,12 +5,19 @@ type Material struct {
 	DiffuseColor        ColorRGBA
 	SpecularColor       ColorRGBA
 	Shininess           float64
-	Transparency        float64
+	Transparency        float64 // 0 is opaque, 1 fully transparent
 	Reflectivity        float64
 	DiffuseTexture      *Texture
 	SpecularTexture     *Texture
 	NormalTexture       *Texture
-	TransparencyTexture *Texture
+	TransparencyTexture *Texture // Opacity in the red channel, like an MTL map_d
+	AlphaCutoff         float64  // Diffuse texels with less alpha are discarded (0 disables)
+
+	// Metallic-roughness parameters from glTF. The renderer shades with
+	// SpecularColor and Shininess, which importers derive from these.
+	Metallic                 float64
+	Roughness                float64
+	MetallicRoughnessTexture *Texture // Roughness in green, metalness in blue
 }
 
 func NewMaterial(name string) *Material {
@@ -23,3 +30,31 @@ func NewMaterial(name string) *Material {
 		Reflectivity:  0.0,
 	}
 }
+
+// IsTransparent reports whether surfaces with this material need blending.
+// Alpha-tested textures are cut out instead, so they don't count.
+func (m *Material) IsTransparent() bool {
+	return m.Transparency > 0 || m.DiffuseColor.A < 1.0 || m.TransparencyTexture != nil ||
+		(m.DiffuseTexture != nil && m.DiffuseTexture.HasAlpha && !m.IsAlphaTested())
+}
+
+// IsAlphaTested reports whether fragments must be checked against AlphaCutoff
+func (m *Material) IsAlphaTested() bool {
+	return m.AlphaCutoff > 0 && m.DiffuseTexture != nil
+}
+
+// IsCutout reports whether the diffuse texel at u, v falls below the alpha cutoff
+func (m *Material) IsCutout(u, v float64) bool {
+	return m.DiffuseTexture.Sample(u, v).A < m.AlphaCutoff
+}
+
+// Opacity combines the material's transparency with the alpha of a diffuse
+// sample and, when there is a transparency texture, its value at u, v
+func (m *Material) Opacity(diffuseAlpha, u, v float64) float64 {
+	opacity := diffuseAlpha * (1 - m.Transparency)
+	if m.TransparencyTexture != nil {
+		sample := m.TransparencyTexture.Sample(u, v)
+		opacity *= float64(sample.R) / 255.0 * sample.A
+	}
+	return opacity
+}
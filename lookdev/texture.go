@@ -4,6 +4,7 @@ import (
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
 	"math"
 	"os"
 )
@@ -11,6 +12,8 @@ import (
 type Texture struct {
 	Width, Height int
 	Pixels        []ColorRGBA
+	HasAlpha      bool   // Whether any texel is less than fully opaque
+	Path          string // File it was loaded from, empty for embedded textures
 }
 
 func LoadTexture(filename string) (*Texture, error) {
@@ -20,7 +23,17 @@ func LoadTexture(filename string) (*Texture, error) {
 	}
 	defer file.Close()
 
-	img, _, err := image.Decode(file)
+	tex, err := DecodeTexture(file)
+	if err != nil {
+		return nil, err
+	}
+	tex.Path = filename
+	return tex, nil
+}
+
+// DecodeTexture reads a PNG or JPEG image, such as one embedded in a model file
+func DecodeTexture(r io.Reader) (*Texture, error) {
+	img, _, err := image.Decode(r)
 	if err != nil {
 		return nil, err
 	}
@@ -29,6 +42,7 @@ func LoadTexture(filename string) (*Texture, error) {
 	width := bounds.Dx()
 	height := bounds.Dy()
 	pixels := make([]ColorRGBA, width*height)
+	hasAlpha := false
 
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
@@ -39,23 +53,40 @@ func LoadTexture(filename string) (*Texture, error) {
 				B: uint8(b >> 8),
 				A: float64(a>>8) / 255.0, // This looks correct
 			}
+			if a>>8 < 255 {
+				hasAlpha = true
+			}
 		}
 	}
 
 	return &Texture{
-		Width:  width,
-		Height: height,
-		Pixels: pixels,
+		Width:    width,
+		Height:   height,
+		Pixels:   pixels,
+		HasAlpha: hasAlpha,
 	}, nil
 }
 
+// ToImage copies the texels into an image, for example to encode them as PNG
+func (t *Texture) ToImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, t.Width, t.Height))
+	for i, p := range t.Pixels {
+		img.Pix[i*4] = p.R
+		img.Pix[i*4+1] = p.G
+		img.Pix[i*4+2] = p.B
+		img.Pix[i*4+3] = uint8(math.Round(p.A * 255))
+	}
+	return img
+}
+
 func (t *Texture) Sample(u, v float64) ColorRGBA {
 	// Wrap texture coordinates
 	u = u - math.Floor(u)
 	v = v - math.Floor(v)
 
+	// Texture coordinates start at the bottom-left, image rows at the top
 	x := int(u * float64(t.Width))
-	y := int(v * float64(t.Height))
+	y := int((1 - v) * float64(t.Height))
 
 	// Clamp to texture dimensions
 	x = max(0, min(x, t.Width-1))
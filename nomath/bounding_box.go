@@ -22,3 +22,22 @@ func (b *BoundingBox) Contains(point Vec3) bool {
 		point.Y >= b.Min.Y && point.Y <= b.Max.Y &&
 		point.Z >= b.Min.Z && point.Z <= b.Max.Z
 }
+
+// Union returns the smallest box containing both boxes
+func (b *BoundingBox) Union(other *BoundingBox) *BoundingBox {
+	return &BoundingBox{Min: Min(b.Min, other.Min), Max: Max(b.Max, other.Max)}
+}
+
+// Corners returns the eight corners of the box
+func (b *BoundingBox) Corners() [8]Vec3 {
+	return [8]Vec3{
+		{X: b.Min.X, Y: b.Min.Y, Z: b.Min.Z},
+		{X: b.Max.X, Y: b.Min.Y, Z: b.Min.Z},
+		{X: b.Min.X, Y: b.Max.Y, Z: b.Min.Z},
+		{X: b.Max.X, Y: b.Max.Y, Z: b.Min.Z},
+		{X: b.Min.X, Y: b.Min.Y, Z: b.Max.Z},
+		{X: b.Max.X, Y: b.Min.Y, Z: b.Max.Z},
+		{X: b.Min.X, Y: b.Max.Y, Z: b.Max.Z},
+		{X: b.Max.X, Y: b.Max.Y, Z: b.Max.Z},
+	}
+}
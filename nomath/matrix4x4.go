@@ -71,6 +71,38 @@ func RotationZMatrix(angle float64) Mat4 {
 	}
 }
 
+// QuaternionMatrix creates a rotation matrix from a unit quaternion (x, y, z, w)
+func QuaternionMatrix(x, y, z, w float64) Mat4 {
+	return Mat4{
+		1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+		2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+		2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+		0, 0, 0, 1,
+	}
+}
+
+// OrthographicMatrix creates an OpenGL-style orthographic projection matrix
+func OrthographicMatrix(left, right, bottom, top, near, far float64) Mat4 {
+	return Mat4{
+		2 / (right - left), 0, 0, 0,
+		0, 2 / (top - bottom), 0, 0,
+		0, 0, -2 / (far - near), 0,
+		-(right + left) / (right - left), -(top + bottom) / (top - bottom), -(far + near) / (far - near), 1,
+	}
+}
+
+// PerspectiveMatrix creates an OpenGL-style perspective projection matrix.
+// fovY is the full vertical field of view in radians.
+func PerspectiveMatrix(fovY, aspect, near, far float64) Mat4 {
+	f := 1.0 / math.Tan(fovY/2)
+	return Mat4{
+		f / aspect, 0, 0, 0,
+		0, f, 0, 0,
+		0, 0, (far + near) / (near - far), -1,
+		0, 0, (2 * far * near) / (near - far), 0,
+	}
+}
+
 // Inverse returns the inverse of the matrix.
 // If the matrix is non-invertible, it returns the identity matrix.
 func (m Mat4) Inverse() Mat4 {
@@ -120,6 +152,14 @@ func (m Mat4) Transpose() Mat4 {
 	}
 }
 
+// Determinant3x3 returns the determinant of the upper-left 3x3 part, which is
+// negative when the matrix mirrors geometry
+func (m Mat4) Determinant3x3() float64 {
+	return m[0]*(m[5]*m[10]-m[9]*m[6]) -
+		m[4]*(m[1]*m[10]-m[9]*m[2]) +
+		m[8]*(m[1]*m[6]-m[5]*m[2])
+}
+
 func (m Mat4) ToEulerAnglesYXZ() Vec3 {
 	// Matrix indices for column-major 16-element array:
 	// 0  4  8  12
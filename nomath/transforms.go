@@ -162,6 +162,22 @@ func (t *Transform) LookAt(target Vec3, worldUp Vec3) {
 	t.Rotation = rotMat.ToEulerAnglesYXZ()
 }
 
+// RotationFromMatrix returns the Euler angles that make a Transform rotate
+// like the (orthonormal) rotation part of m. The Transform axis matrices turn
+// the opposite way to the textbook ones, hence the negated angles.
+func RotationFromMatrix(m Mat4) Vec3 {
+	// Decompose m = Ry(yaw) * Rx(pitch) * Rz(roll) with textbook matrices
+	pitch := math.Asin(math.Max(-1, math.Min(1, -m[9])))
+	var yaw, roll float64
+	if math.Cos(pitch) > 1e-6 {
+		yaw = math.Atan2(m[8], m[10])
+		roll = math.Atan2(m[1], m[5])
+	} else {
+		yaw = math.Atan2(-m[2], m[0]) // Gimbal lock, roll folds into yaw
+	}
+	return Vec3{X: -pitch, Y: -yaw, Z: -roll}
+}
+
 // Equals checks if two transforms are approximately equal
 func (t *Transform) Equals(other *Transform) bool {
 	const epsilon = 0.0001
@@ -205,10 +221,10 @@ func (t *Transform) UpdateModelMatrix() {
 		t.Scale.Z,
 	)
 
-	// Correct multiplication order: Scale -> Rotation -> Translation
+	// Scale -> Rotation -> Translation, so the matrices multiply right to left
 	t.ModelMatrix = IdentityMatrix().
-		Multiply(scale).
+		Multiply(translation).
 		Multiply(rotation).
-		Multiply(translation)
+		Multiply(scale)
 	t.Dirty = false
 }